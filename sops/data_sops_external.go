@@ -5,25 +5,45 @@ import (
 	"io/ioutil"
 	"strings"
 
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-var _ datasource.DataSource = &externalDataSource{}
+var _ datasource.DataSourceWithConfigure = &externalDataSource{}
 
 func newExternalDataSource() datasource.DataSource {
 	return &externalDataSource{}
 }
 
-type externalDataSource struct{}
+type externalDataSource struct {
+	stores *config.StoresConfig
+}
 
 type externalDataSourceModel struct {
-	InputType types.String `tfsdk:"input_type"`
-	Source    types.String `tfsdk:"source"`
-	Data      types.Map    `tfsdk:"data"`
-	Raw       types.String `tfsdk:"raw"`
-	Id        types.String `tfsdk:"id"`
+	InputType     types.String `tfsdk:"input_type"`
+	Source        types.String `tfsdk:"source"`
+	DocumentIndex types.Int64  `tfsdk:"document_index"`
+	KeySeparator  types.String `tfsdk:"key_separator"`
+	EscapeKeys    types.Bool   `tfsdk:"escape_keys"`
+	Ini           types.Object `tfsdk:"ini"`
+	Data          types.Map    `tfsdk:"data"`
+	Documents     types.List   `tfsdk:"documents"`
+	Raw           types.String `tfsdk:"raw"`
+	RequiredKeys  types.List   `tfsdk:"required_keys"`
+	Schema        types.String `tfsdk:"schema"`
+	IncludeValues types.Bool   `tfsdk:"include_values"`
+	HashSalt      types.String `tfsdk:"hash_salt"`
+	DataSha256    types.Map    `tfsdk:"data_sha256"`
+	Id            types.String `tfsdk:"id"`
+}
+
+func (d *externalDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	d.stores = configuredStores(req.ProviderData)
 }
 
 func (d *externalDataSource) Metadata(_ context.Context, _ datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -35,13 +55,47 @@ func (d *externalDataSource) Schema(_ context.Context, _ datasource.SchemaReques
 		Description: "Decrypt sops-encrypted data from external commands",
 		Attributes: map[string]schema.Attribute{
 			"input_type": schema.StringAttribute{
-				Description: "Type of the input data: json, yaml, dotenv, ini, raw",
+				Description: "Type of the input data: json, yaml, dotenv, ini, toml, properties, hcl, raw. Detected from the sops metadata by default",
 				Optional:    true,
 			},
 			"source": schema.StringAttribute{
 				Description: "A string with sops-encrypted data",
 				Required:    true,
 			},
+			"document_index": schema.Int64Attribute{
+				Description: "Index of the document in a multi-document YAML file used to populate data. Defaults to 0",
+				Optional:    true,
+			},
+			"key_separator": schema.StringAttribute{
+				Description: "Separator used to join the keys of nested data in data. Defaults to .",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"escape_keys": schema.BoolAttribute{
+				Description: "Escape occurrences of the key separator in keys with a backslash, so that keys containing the separator can't collide with nested keys",
+				Optional:    true,
+			},
+			"ini": iniOptionsAttribute(),
+			"required_keys": schema.ListAttribute{
+				Description: "Keys that must have a value in the decrypted data, in the same form as the keys of data",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"schema": schema.StringAttribute{
+				Description: "JSON Schema the decrypted data must conform to",
+				Optional:    true,
+			},
+			"include_values": schema.BoolAttribute{
+				Description: "Set to false to leave the decrypted values out of data, documents and raw, so they aren't stored in the state. Defaults to true",
+				Optional:    true,
+			},
+			"hash_salt": schema.StringAttribute{
+				Description: "Key used to hash values in data_sha256 with HMAC-SHA256 instead of plain SHA-256",
+				Optional:    true,
+				Sensitive:   true,
+			},
 
 			"data": schema.MapAttribute{
 				Description: "Decrypted data",
@@ -49,6 +103,31 @@ func (d *externalDataSource) Schema(_ context.Context, _ datasource.SchemaReques
 				Sensitive:   true,
 				ElementType: types.StringType,
 			},
+			"documents": schema.ListNestedAttribute{
+				Description: "Decrypted data of each document in the file. Only YAML files can contain more than one document",
+				Computed:    true,
+				Sensitive:   true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"data": schema.MapAttribute{
+							Description: "Decrypted data",
+							Computed:    true,
+							Sensitive:   true,
+							ElementType: types.StringType,
+						},
+						"raw": schema.StringAttribute{
+							Description: "Raw decrypted content of the document",
+							Computed:    true,
+							Sensitive:   true,
+						},
+					},
+				},
+			},
+			"data_sha256": schema.MapAttribute{
+				Description: "SHA-256 of each value in data, hex encoded, to detect changes without storing the values",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
 			"raw": schema.StringAttribute{
 				Description: "Raw decrypted content",
 				Computed:    true,
@@ -77,28 +156,66 @@ func (d *externalDataSource) Read(ctx context.Context, req datasource.ReadReques
 		return
 	}
 
-	format := config.InputType.ValueString()
+	format, err := resolveInputType("", content, config.InputType)
+	if err != nil {
+		resp.Diagnostics.AddError("Unknown input type", err.Error())
+		return
+	}
 	if err := validateInputType(format); err != nil {
 		resp.Diagnostics.AddError("Invalid input type", err.Error())
 		return
 	}
 
-	data, raw, err := readData(content, format)
+	opts, optsDiags := readOptionsModel{
+		KeySeparator: config.KeySeparator,
+		EscapeKeys:   config.EscapeKeys,
+		Ini:          config.Ini,
+		Stores:       d.stores,
+	}.readOptions(ctx)
+	resp.Diagnostics.Append(optsDiags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	docs, raw, err := readDocuments(newLogContext(ctx, ""), content, format, opts)
+	if err != nil {
+		addSopsErrors(&resp.Diagnostics, "Error reading data", err)
+		return
+	}
+	doc, err := selectDocument(docs, config.DocumentIndex)
 	if err != nil {
-		resp.Diagnostics.AddError("Error reading data", err.Error())
+		resp.Diagnostics.AddAttributeError(tfpath.Root("document_index"), "Invalid document index", err.Error())
 		return
 	}
+	addCollisionWarnings(&resp.Diagnostics, doc.collisions)
+	validateDocument(ctx, doc, config.RequiredKeys, config.Schema, opts, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data := doc.Data
 
 	m, mapDiags := types.MapValueFrom(ctx, types.StringType, data)
 	resp.Diagnostics.Append(mapDiags...)
+	hashes, hashDiags := types.MapValueFrom(ctx, types.StringType, hashData(data, config.HashSalt.ValueString()))
+	resp.Diagnostics.Append(hashDiags...)
+	l, listDiags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: documentAttrTypes}, docs)
+	resp.Diagnostics.Append(listDiags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	config.Data = m
+	config.Documents = l
 	config.Raw = types.StringValue(raw)
+	config.DataSha256 = hashes
 	config.Id = types.StringValue("-")
 
+	if !config.IncludeValues.IsNull() && !config.IncludeValues.ValueBool() {
+		config.Data = types.MapNull(types.StringType)
+		config.Documents = types.ListNull(types.ObjectType{AttrTypes: documentAttrTypes})
+		config.Raw = types.StringNull()
+	}
+
 	diags = resp.State.Set(ctx, config)
 	resp.Diagnostics.Append(diags...)
 }
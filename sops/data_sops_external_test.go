@@ -172,3 +172,80 @@ func TestDataSourceSopsExternal_json(t *testing.T) {
 		},
 	})
 }
+
+const configTestDataSourceSopsExternal_multiDocument = `
+data "sops_external" "test_multi" {
+  source     = file("%s/test-fixtures/multi-document.yaml")
+  input_type = "yaml"
+}`
+
+func TestDataSourceSopsExternal_multiDocument(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsExternal_multiDocument, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_external.test_multi", "data.name", "first"),
+					resource.TestCheckResourceAttr("data.sops_external.test_multi", "documents.#", "2"),
+					resource.TestCheckResourceAttr("data.sops_external.test_multi", "documents.1.data.name", "second"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsExternal_detectedType = `
+data "sops_external" "test_detected" {
+  source = file("%s/test-fixtures/basic.yaml")
+}`
+
+func TestDataSourceSopsExternal_detectedType(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsExternal_detectedType, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_external.test_detected", "data.hello", "world"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsExternal_saltedHashes = `
+data "sops_external" "test_hashes" {
+  source    = file("%s/test-fixtures/basic.yaml")
+  hash_salt = "salt"
+}`
+
+func TestDataSourceSopsExternal_saltedHashes(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsExternal_saltedHashes, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_external.test_hashes", "data.hello", "world"),
+					resource.TestCheckResourceAttr("data.sops_external.test_hashes", "data_sha256.hello", "1d98aa0c2569b9aefb24276c285080e2e8b91e4d3cd109e732ce86ba0fa6ac84"),
+				),
+			},
+		},
+	})
+}
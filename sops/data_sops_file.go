@@ -4,27 +4,49 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
-	"path"
 
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-var _ datasource.DataSource = &fileDataSource{}
+var _ datasource.DataSourceWithConfigure = &fileDataSource{}
 
 func newFileDataSource() datasource.DataSource {
 	return &fileDataSource{}
 }
 
-type fileDataSource struct{}
+type fileDataSource struct {
+	stores *config.StoresConfig
+}
 
 type fileDataSourceModel struct {
-	InputType  types.String `tfsdk:"input_type"`
-	SourceFile types.String `tfsdk:"source_file"`
-	Data       types.Map    `tfsdk:"data"`
-	Raw        types.String `tfsdk:"raw"`
-	Id         types.String `tfsdk:"id"`
+	InputType     types.String `tfsdk:"input_type"`
+	SourceFile    types.String `tfsdk:"source_file"`
+	DocumentIndex types.Int64  `tfsdk:"document_index"`
+	KeySeparator  types.String `tfsdk:"key_separator"`
+	EscapeKeys    types.Bool   `tfsdk:"escape_keys"`
+	Ini           types.Object `tfsdk:"ini"`
+	Decode        types.String `tfsdk:"decode"`
+	Data          types.Map    `tfsdk:"data"`
+	Documents     types.List   `tfsdk:"documents"`
+	Secrets       types.Map    `tfsdk:"secrets"`
+	Raw           types.String `tfsdk:"raw"`
+	RequiredKeys  types.List   `tfsdk:"required_keys"`
+	Schema        types.String `tfsdk:"schema"`
+	IncludeValues types.Bool   `tfsdk:"include_values"`
+	HashSalt      types.String `tfsdk:"hash_salt"`
+	DataSha256    types.Map    `tfsdk:"data_sha256"`
+	Id            types.String `tfsdk:"id"`
+}
+
+func (d *fileDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	d.stores = configuredStores(req.ProviderData)
 }
 
 func (d *fileDataSource) Metadata(_ context.Context, _ datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -36,13 +58,54 @@ func (d *fileDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, r
 		Description: "Decrypt sops-encrypted files",
 		Attributes: map[string]schema.Attribute{
 			"input_type": schema.StringAttribute{
-				Description: "Type of the input file: json, yaml, dotenv, ini, raw",
+				Description: "Type of the input file: json, yaml, dotenv, ini, toml, properties, hcl, raw. Detected from the file extension or the sops metadata by default",
 				Optional:    true,
 			},
 			"source_file": schema.StringAttribute{
 				Description: "Path to the encrypted file",
 				Required:    true,
 			},
+			"document_index": schema.Int64Attribute{
+				Description: "Index of the document in a multi-document YAML file used to populate data. Defaults to 0",
+				Optional:    true,
+			},
+			"key_separator": schema.StringAttribute{
+				Description: "Separator used to join the keys of nested data in data. Defaults to .",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"escape_keys": schema.BoolAttribute{
+				Description: "Escape occurrences of the key separator in keys with a backslash, so that keys containing the separator can't collide with nested keys",
+				Optional:    true,
+			},
+			"ini": iniOptionsAttribute(),
+			"decode": schema.StringAttribute{
+				Description: "Decoding applied to the data of each document. kubernetes_secret replaces Kubernetes Secret manifests with their stringData and base64-decoded data values",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(decodeKubernetesSecret),
+				},
+			},
+			"required_keys": schema.ListAttribute{
+				Description: "Keys that must have a value in the decrypted data, in the same form as the keys of data",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"schema": schema.StringAttribute{
+				Description: "JSON Schema the decrypted data must conform to",
+				Optional:    true,
+			},
+			"include_values": schema.BoolAttribute{
+				Description: "Set to false to leave the decrypted values out of data, documents, secrets and raw, so they aren't stored in the state. Defaults to true",
+				Optional:    true,
+			},
+			"hash_salt": schema.StringAttribute{
+				Description: "Key used to hash values in data_sha256 with HMAC-SHA256 instead of plain SHA-256",
+				Optional:    true,
+				Sensitive:   true,
+			},
 
 			"data": schema.MapAttribute{
 				Description: "Decrypted data",
@@ -50,6 +113,37 @@ func (d *fileDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, r
 				Sensitive:   true,
 				ElementType: types.StringType,
 			},
+			"documents": schema.ListNestedAttribute{
+				Description: "Decrypted data of each document in the file. Only YAML files can contain more than one document",
+				Computed:    true,
+				Sensitive:   true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"data": schema.MapAttribute{
+							Description: "Decrypted data",
+							Computed:    true,
+							Sensitive:   true,
+							ElementType: types.StringType,
+						},
+						"raw": schema.StringAttribute{
+							Description: "Raw decrypted content of the document",
+							Computed:    true,
+							Sensitive:   true,
+						},
+					},
+				},
+			},
+			"secrets": schema.MapAttribute{
+				Description: "Values of the Kubernetes Secrets in the file, keyed by namespace/name. Only set when decode is kubernetes_secret",
+				Computed:    true,
+				Sensitive:   true,
+				ElementType: types.MapType{ElemType: types.StringType},
+			},
+			"data_sha256": schema.MapAttribute{
+				Description: "SHA-256 of each value in data, hex encoded, to detect changes without storing the values",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
 			"raw": schema.StringAttribute{
 				Description: "Raw decrypted content",
 				Computed:    true,
@@ -78,49 +172,86 @@ func (d *fileDataSource) Read(ctx context.Context, req datasource.ReadRequest, r
 		return
 	}
 
-	var format string
-	if !config.InputType.IsNull() {
-		format = config.InputType.ValueString()
-	} else {
-		switch ext := path.Ext(sourceFile); ext {
-		case ".json":
-			format = "json"
-		case ".yaml", ".yml":
-			format = "yaml"
-		case ".env":
-			format = "dotenv"
-		case ".ini":
-			format = "ini"
-		default:
-			resp.Diagnostics.AddError(
-				"Unknown file type",
-				fmt.Sprintf("Don't know how to decode file with extension %s, set input_type as appropriate", ext),
-			)
-			return
-		}
+	format, err := resolveInputType(sourceFile, content, config.InputType)
+	if err != nil {
+		resp.Diagnostics.AddError("Unknown file type", err.Error())
+		return
 	}
 
 	if err := validateInputType(format); err != nil {
 		resp.Diagnostics.AddError("Invalid input type", err.Error())
 		return
 	}
+	if config.Decode.ValueString() == decodeKubernetesSecret && format != "yaml" && format != "json" {
+		resp.Diagnostics.AddAttributeError(tfpath.Root("decode"), "Invalid decoding", fmt.Sprintf("Kubernetes Secret manifests must be yaml or json, not %s", format))
+		return
+	}
+
+	opts, optsDiags := readOptionsModel{
+		KeySeparator: config.KeySeparator,
+		EscapeKeys:   config.EscapeKeys,
+		Ini:          config.Ini,
+		Stores:       d.stores,
+	}.readOptions(ctx)
+	resp.Diagnostics.Append(optsDiags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	data, raw, err := readData(content, format)
+	docs, raw, err := readDocuments(newLogContext(ctx, sourceFile), content, format, opts)
 	if err != nil {
-		resp.Diagnostics.AddError("Error reading data", err.Error())
+		addSopsErrors(&resp.Diagnostics, "Error reading data", err)
 		return
 	}
 
+	secrets := types.MapNull(types.MapType{ElemType: types.StringType})
+	if config.Decode.ValueString() == decodeKubernetesSecret {
+		decoded, err := decodeKubernetesSecrets(docs)
+		if err != nil {
+			resp.Diagnostics.AddError("Error decoding Kubernetes Secrets", err.Error())
+			return
+		}
+		var mapDiags diag.Diagnostics
+		secrets, mapDiags = types.MapValueFrom(ctx, types.MapType{ElemType: types.StringType}, decoded)
+		resp.Diagnostics.Append(mapDiags...)
+	}
+
+	doc, err := selectDocument(docs, config.DocumentIndex)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(tfpath.Root("document_index"), "Invalid document index", err.Error())
+		return
+	}
+	addCollisionWarnings(&resp.Diagnostics, doc.collisions)
+	validateDocument(ctx, doc, config.RequiredKeys, config.Schema, opts, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data := doc.Data
+
 	m, mapDiags := types.MapValueFrom(ctx, types.StringType, data)
 	resp.Diagnostics.Append(mapDiags...)
+	hashes, hashDiags := types.MapValueFrom(ctx, types.StringType, hashData(data, config.HashSalt.ValueString()))
+	resp.Diagnostics.Append(hashDiags...)
+	l, listDiags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: documentAttrTypes}, docs)
+	resp.Diagnostics.Append(listDiags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	config.Data = m
+	config.Documents = l
+	config.Secrets = secrets
 	config.Raw = types.StringValue(raw)
+	config.DataSha256 = hashes
 	config.Id = types.StringValue("-")
 
+	if !config.IncludeValues.IsNull() && !config.IncludeValues.ValueBool() {
+		config.Data = types.MapNull(types.StringType)
+		config.Documents = types.ListNull(types.ObjectType{AttrTypes: documentAttrTypes})
+		config.Raw = types.StringNull()
+		config.Secrets = types.MapNull(types.MapType{ElemType: types.StringType})
+	}
+
 	diags = resp.State.Set(ctx, config)
 	resp.Diagnostics.Append(diags...)
 }
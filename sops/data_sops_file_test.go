@@ -3,6 +3,7 @@ package sops
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
@@ -167,3 +168,268 @@ func TestDataSourceSopsFile_json(t *testing.T) {
 		},
 	})
 }
+
+const configTestDataSourceSopsFile_multiDocument = `
+data "sops_file" "test_multi" {
+  source_file    = "%s/test-fixtures/multi-document.yaml"
+  document_index = 1
+}`
+
+func TestDataSourceSopsFile_multiDocument(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_multiDocument, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_multi", "data.name", "second"),
+					resource.TestCheckResourceAttr("data.sops_file.test_multi", "documents.#", "2"),
+					resource.TestCheckResourceAttr("data.sops_file.test_multi", "documents.0.data.db.password", "foo"),
+					resource.TestCheckResourceAttr("data.sops_file.test_multi", "documents.1.data.db.password", "bar"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_keySeparator = `
+data "sops_file" "test_separator" {
+  source_file   = "%s/test-fixtures/nested.yaml"
+  key_separator = "/"
+}`
+
+func TestDataSourceSopsFile_keySeparator(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_keySeparator, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_separator", "data.db/user", "foo"),
+					resource.TestCheckResourceAttr("data.sops_file.test_separator", "data.db/password", "bar"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_iniOptions = `
+data "sops_file" "test_ini" {
+  source_file = "%s/test-fixtures/sections.ini"
+  ini = {
+    nested_sections = true
+    typed_values    = true
+  }
+}`
+
+func TestDataSourceSopsFile_iniOptions(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_iniOptions, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.port", "5432"),
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.db.primary.host", "primary.example"),
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.db.primary.enabled", "true"),
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.db.replica.weight", "0.5"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_toml = `
+data "sops_file" "test_toml" {
+  source_file = "%s/test-fixtures/secrets.toml"
+}`
+
+func TestDataSourceSopsFile_toml(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_toml, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_toml", "data.password", "P@ssw0rd"),
+					resource.TestCheckResourceAttr("data.sops_file.test_toml", "data.port", "5432"),
+					resource.TestCheckResourceAttr("data.sops_file.test_toml", "data.db.user", "foo"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_properties = `
+data "sops_file" "test_properties" {
+  source_file = "%s/test-fixtures/secrets.properties"
+}`
+
+func TestDataSourceSopsFile_properties(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_properties, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_properties", "data.db.password", "P@ssw0rd"),
+					resource.TestCheckResourceAttr("data.sops_file.test_properties", "data.db.user", "foo"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_hcl = `
+data "sops_file" "test_hcl" {
+  source_file = "%s/test-fixtures/secrets.enc.tfvars"
+}`
+
+func TestDataSourceSopsFile_hcl(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_hcl, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_hcl", "data.db_password", "P@ssw0rd"),
+					resource.TestCheckResourceAttr("data.sops_file.test_hcl", "data.replicas", "3"),
+					resource.TestCheckResourceAttr("data.sops_file.test_hcl", "data.admins.1", "bob"),
+					resource.TestCheckResourceAttr("data.sops_file.test_hcl", "data.smtp.user", "mailer"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_kubernetesSecret = `
+data "sops_file" "test_k8s" {
+  source_file = "%s/test-fixtures/kubernetes-secrets.yaml"
+  decode      = "kubernetes_secret"
+}`
+
+func TestDataSourceSopsFile_kubernetesSecret(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_kubernetesSecret, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_k8s", "data.password", "P@ssw0rd"),
+					resource.TestCheckResourceAttr("data.sops_file.test_k8s", "data.username", "admin"),
+					resource.TestCheckResourceAttr("data.sops_file.test_k8s", "secrets.apps/db.password", "P@ssw0rd"),
+					resource.TestCheckResourceAttr("data.sops_file.test_k8s", "secrets.default/api.token", "s3cr3t"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_unknownExtension = `
+data "sops_file" "test_unknown_extension" {
+  source_file = "%s/test-fixtures/basic.secret"
+}`
+
+func TestDataSourceSopsFile_unknownExtension(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_unknownExtension, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_unknown_extension", "data.hello", "world"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_hashesOnly = `
+data "sops_file" "test_hashes" {
+  source_file    = "%s/test-fixtures/basic.yaml"
+  include_values = false
+}`
+
+func TestDataSourceSopsFile_hashesOnly(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_hashesOnly, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_hashes", "data_sha256.hello", "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"),
+					resource.TestCheckNoResourceAttr("data.sops_file.test_hashes", "data.%"),
+					resource.TestCheckNoResourceAttr("data.sops_file.test_hashes", "raw"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_requiredKeys = `
+data "sops_file" "test_required" {
+  source_file   = "%s/test-fixtures/nested.yaml"
+  required_keys = ["db.user", "db.host"]
+}`
+
+func TestDataSourceSopsFile_requiredKeys(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_requiredKeys, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config,
+				ExpectError: regexp.MustCompile("no value for: db.host"),
+			},
+		},
+	})
+}
@@ -0,0 +1,203 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSourceWithConfigure = &filesDataSource{}
+
+func newFilesDataSource() datasource.DataSource {
+	return &filesDataSource{}
+}
+
+type filesDataSource struct {
+	stores *config.StoresConfig
+}
+
+type filesDataSourceModel struct {
+	Directory   types.String `tfsdk:"directory"`
+	Patterns    types.List   `tfsdk:"patterns"`
+	InputType   types.String `tfsdk:"input_type"`
+	Parallelism types.Int64  `tfsdk:"parallelism"`
+	Files       types.Map    `tfsdk:"files"`
+	Id          types.String `tfsdk:"id"`
+}
+
+type filesDataSourceFileModel struct {
+	Data map[string]string `tfsdk:"data"`
+	Raw  string            `tfsdk:"raw"`
+}
+
+var filesDataSourceFileAttrTypes = map[string]attr.Type{
+	"data": types.MapType{ElemType: types.StringType},
+	"raw":  types.StringType,
+}
+
+const defaultFilesParallelism = 4
+
+func (d *filesDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	d.stores = configuredStores(req.ProviderData)
+}
+
+func (d *filesDataSource) Metadata(_ context.Context, _ datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = "sops_files"
+}
+
+func (d *filesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Decrypt all sops-encrypted files in a directory matching a set of patterns",
+		Attributes: map[string]schema.Attribute{
+			"directory": schema.StringAttribute{
+				Description: "Directory containing the encrypted files",
+				Required:    true,
+			},
+			"patterns": schema.ListAttribute{
+				Description: "Glob patterns, relative to directory, selecting the files to decrypt",
+				Required:    true,
+				ElementType: types.StringType,
+			},
+			"input_type": schema.StringAttribute{
+				Description: "Type of all input files: json, yaml, dotenv, ini, toml, properties, hcl, raw. Detected from each file's extension or sops metadata by default",
+				Optional:    true,
+			},
+			"parallelism": schema.Int64Attribute{
+				Description: fmt.Sprintf("Maximum number of files decrypted concurrently. Defaults to %d", defaultFilesParallelism),
+				Optional:    true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+
+			"files": schema.MapNestedAttribute{
+				Description: "Decrypted files, keyed by their path relative to directory",
+				Computed:    true,
+				Sensitive:   true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"data": schema.MapAttribute{
+							Description: "Decrypted data",
+							Computed:    true,
+							Sensitive:   true,
+							ElementType: types.StringType,
+						},
+						"raw": schema.StringAttribute{
+							Description: "Raw decrypted content",
+							Computed:    true,
+							Sensitive:   true,
+						},
+					},
+				},
+			},
+			"id": schema.StringAttribute{
+				Description: "Unique identifier for this data source",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (d *filesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config filesDataSourceModel
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var patterns []string
+	resp.Diagnostics.Append(config.Patterns.ElementsAs(ctx, &patterns, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.InputType.IsNull() {
+		if err := validateInputType(config.InputType.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Invalid input type", err.Error())
+			return
+		}
+	}
+
+	parallelism := defaultFilesParallelism
+	if !config.Parallelism.IsNull() {
+		parallelism = int(config.Parallelism.ValueInt64())
+	}
+
+	directory := config.Directory.ValueString()
+	files, err := findFiles(directory, patterns)
+	if err != nil {
+		resp.Diagnostics.AddError("Error listing files", err.Error())
+		return
+	}
+
+	results := make([]filesDataSourceFileModel, len(files))
+	errs := make([]error, len(files))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, f := range files {
+		wg.Add(1)
+		go func(i int, f string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			filename := filepath.Join(directory, filepath.FromSlash(f))
+			results[i], errs[i] = readFile(newLogContext(ctx, filename), filename, config.InputType, d.stores)
+		}(i, f)
+	}
+	wg.Wait()
+
+	decrypted := make(map[string]filesDataSourceFileModel, len(files))
+	for i, f := range files {
+		if errs[i] != nil {
+			addSopsErrors(&resp.Diagnostics, fmt.Sprintf("Error reading %s", f), errs[i])
+			continue
+		}
+		decrypted[f] = results[i]
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	m, mapDiags := types.MapValueFrom(ctx, types.ObjectType{AttrTypes: filesDataSourceFileAttrTypes}, decrypted)
+	resp.Diagnostics.Append(mapDiags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config.Files = m
+	config.Id = types.StringValue(directory)
+
+	diags = resp.State.Set(ctx, config)
+	resp.Diagnostics.Append(diags...)
+}
+
+// readFile decrypts a single file, using the input type of its extension
+// unless one is given explicitly
+func readFile(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig) (filesDataSourceFileModel, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return filesDataSourceFileModel{}, err
+	}
+
+	format, err := resolveInputType(filename, content, inputType)
+	if err != nil {
+		return filesDataSourceFileModel{}, err
+	}
+
+	data, raw, err := readData(ctx, content, format, stores)
+	if err != nil {
+		return filesDataSourceFileModel{}, err
+	}
+	return filesDataSourceFileModel{Data: data, Raw: raw}, nil
+}
@@ -0,0 +1,64 @@
+package sops
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const configTestDataSourceSopsFiles_basic = `
+data "sops_files" "test_basic" {
+  directory = "%s/test-fixtures"
+  patterns  = ["basic.*", "nested.yaml"]
+}`
+
+func TestDataSourceSopsFiles_basic(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFiles_basic, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.%", "3"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.basic.yaml.data.hello", "world"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.basic.json.data.integer", "0"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.nested.yaml.data.db.password", "bar"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFiles_raw = `
+data "sops_files" "test_raw" {
+  directory   = "%s/test-fixtures"
+  patterns    = ["*.txt"]
+  input_type  = "raw"
+  parallelism = 1
+}`
+
+func TestDataSourceSopsFiles_raw(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFiles_raw, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_files.test_raw", "files.raw.txt.raw", "Hello raw world!"),
+				),
+			},
+		},
+	})
+}
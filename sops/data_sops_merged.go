@@ -0,0 +1,183 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSourceWithConfigure = &mergedDataSource{}
+
+func newMergedDataSource() datasource.DataSource {
+	return &mergedDataSource{}
+}
+
+type mergedDataSource struct {
+	stores *config.StoresConfig
+}
+
+type mergedDataSourceModel struct {
+	Sources      types.List    `tfsdk:"sources"`
+	InputType    types.String  `tfsdk:"input_type"`
+	ListStrategy types.String  `tfsdk:"list_strategy"`
+	Data         types.Map     `tfsdk:"data"`
+	Value        types.Dynamic `tfsdk:"value"`
+	Origins      types.Map     `tfsdk:"origins"`
+	Id           types.String  `tfsdk:"id"`
+}
+
+func (d *mergedDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	d.stores = configuredStores(req.ProviderData)
+}
+
+func (d *mergedDataSource) Metadata(_ context.Context, _ datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = "sops_merged"
+}
+
+func (d *mergedDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Decrypt several sops-encrypted files and deep-merge their contents",
+		Attributes: map[string]schema.Attribute{
+			"sources": schema.ListAttribute{
+				Description: "Paths to the encrypted files. Values in later files take precedence",
+				Required:    true,
+				ElementType: types.StringType,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"input_type": schema.StringAttribute{
+				Description: "Type of all input files: json, yaml, dotenv, ini, toml, properties, hcl. Detected from each file's extension or sops metadata by default",
+				Optional:    true,
+			},
+			"list_strategy": schema.StringAttribute{
+				Description: "How lists present in several files are merged: replace or append. Defaults to replace",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(listStrategyReplace, listStrategyAppend),
+				},
+			},
+
+			"data": schema.MapAttribute{
+				Description: "Merged decrypted data",
+				Computed:    true,
+				Sensitive:   true,
+				ElementType: types.StringType,
+			},
+			"value": schema.DynamicAttribute{
+				Description: "Merged decrypted data, as a typed value",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"origins": schema.MapAttribute{
+				Description: "The source file each key in data was taken from",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"id": schema.StringAttribute{
+				Description: "Unique identifier for this data source",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (d *mergedDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config mergedDataSourceModel
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var sources []string
+	resp.Diagnostics.Append(config.Sources.ElementsAs(ctx, &sources, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.InputType.IsNull() {
+		if err := validateInputType(config.InputType.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Invalid input type", err.Error())
+			return
+		}
+		if config.InputType.ValueString() == "raw" {
+			resp.Diagnostics.AddError("Invalid input type", "Raw files have no structure and can't be merged")
+			return
+		}
+	}
+
+	listStrategy := listStrategyReplace
+	if !config.ListStrategy.IsNull() {
+		listStrategy = config.ListStrategy.ValueString()
+	}
+
+	merged := map[string]interface{}{}
+	origins := map[string]interface{}{}
+	for _, source := range sources {
+		data, err := readTree(newLogContext(ctx, source), source, config.InputType, d.stores)
+		if err != nil {
+			addSopsErrors(&resp.Diagnostics, fmt.Sprintf("Error reading %s", source), err)
+			return
+		}
+		merged = deepMerge(merged, data, listStrategy)
+		origins = deepMerge(origins, originTree(data, source).(map[string]interface{}), listStrategy)
+	}
+
+	m, mapDiags := types.MapValueFrom(ctx, types.StringType, flatten(merged))
+	resp.Diagnostics.Append(mapDiags...)
+	o, mapDiags := types.MapValueFrom(ctx, types.StringType, flatten(origins))
+	resp.Diagnostics.Append(mapDiags...)
+	v, dynDiags := toDynamic(ctx, merged)
+	resp.Diagnostics.Append(dynDiags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config.Data = m
+	config.Value = v
+	config.Origins = o
+	config.Id = types.StringValue("-")
+
+	diags = resp.State.Set(ctx, config)
+	resp.Diagnostics.Append(diags...)
+}
+
+// readTree decrypts a single file and returns its unflattened contents, using
+// the input type of its extension unless one is given explicitly
+func readTree(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig) (map[string]interface{}, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := resolveInputType(filename, content, inputType)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "raw" {
+		return nil, fmt.Errorf("Raw files have no structure and can't be merged")
+	}
+
+	cleartext, err := decryptData(ctx, content, format, stores)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseData(cleartext, format, readOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data, nil
+}
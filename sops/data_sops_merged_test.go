@@ -0,0 +1,72 @@
+package sops
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const configTestDataSourceSopsMerged_basic = `
+data "sops_merged" "test_basic" {
+  sources = [
+    "%[1]s/test-fixtures/basic.yaml",
+    "%[1]s/test-fixtures/nested.yaml",
+    "%[1]s/test-fixtures/basic.json",
+  ]
+}`
+
+func TestDataSourceSopsMerged_basic(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsMerged_basic, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_merged.test_basic", "data.hello", "world"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_basic", "data.null_value", "null"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_basic", "data.db.password", "bar"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_basic", "origins.null_value", wd+"/test-fixtures/basic.yaml"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_basic", "origins.db.password", wd+"/test-fixtures/nested.yaml"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_basic", "origins.hello", wd+"/test-fixtures/basic.json"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsMerged_append = `
+data "sops_merged" "test_append" {
+  sources = [
+    "%[1]s/test-fixtures/simple-list.yaml",
+    "%[1]s/test-fixtures/complex-list.yaml",
+  ]
+  list_strategy = "append"
+}`
+
+func TestDataSourceSopsMerged_append(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsMerged_append, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_merged.test_append", "data.a_list.0", "val1"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_append", "data.a_list.3.name", "foo"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_append", "data.a_list.4.name", "bar"),
+				),
+			},
+		},
+	})
+}
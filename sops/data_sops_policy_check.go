@@ -0,0 +1,286 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSource = &policyCheckDataSource{}
+
+func newPolicyCheckDataSource() datasource.DataSource {
+	return &policyCheckDataSource{}
+}
+
+type policyCheckDataSource struct{}
+
+type policyCheckDataSourceModel struct {
+	Directory          types.String `tfsdk:"directory"`
+	Patterns           types.List   `tfsdk:"patterns"`
+	RequiredRecipients types.List   `tfsdk:"required_recipients"`
+	SopsConfig         types.String `tfsdk:"sops_config"`
+	MaxAge             types.String `tfsdk:"max_age"`
+	Files              types.List   `tfsdk:"files"`
+	Passed             types.Bool   `tfsdk:"passed"`
+	Id                 types.String `tfsdk:"id"`
+}
+
+type policyCheckFileModel struct {
+	Path         string   `tfsdk:"path"`
+	Passed       bool     `tfsdk:"passed"`
+	Reasons      []string `tfsdk:"reasons"`
+	Recipients   []string `tfsdk:"recipients"`
+	LastModified string   `tfsdk:"last_modified"`
+}
+
+var policyCheckFileAttrTypes = map[string]attr.Type{
+	"path":          types.StringType,
+	"passed":        types.BoolType,
+	"reasons":       types.ListType{ElemType: types.StringType},
+	"recipients":    types.ListType{ElemType: types.StringType},
+	"last_modified": types.StringType,
+}
+
+func (d *policyCheckDataSource) Metadata(_ context.Context, _ datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = "sops_policy_check"
+}
+
+func (d *policyCheckDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Check that sops-encrypted files comply with a recipient and key age policy, without decrypting them",
+		Attributes: map[string]schema.Attribute{
+			"directory": schema.StringAttribute{
+				Description: "Directory containing the encrypted files",
+				Required:    true,
+			},
+			"patterns": schema.ListAttribute{
+				Description: "Glob patterns, relative to directory, selecting the files to check. Defaults to all files",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"required_recipients": schema.ListAttribute{
+				Description: "Recipients (PGP fingerprints, KMS ARNs, age recipients, ...) every file must be encrypted for",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"sops_config": schema.StringAttribute{
+				Description: "Path to a .sops.yaml file. The recipients of the creation rule matching each file are required as well",
+				Optional:    true,
+			},
+			"max_age": schema.StringAttribute{
+				Description: "Maximum time since each file was last modified by sops, e.g. 2160h",
+				Optional:    true,
+			},
+
+			"files": schema.ListNestedAttribute{
+				Description: "Result of the check for each file",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path": schema.StringAttribute{
+							Description: "Path of the file, relative to directory",
+							Computed:    true,
+						},
+						"passed": schema.BoolAttribute{
+							Description: "Whether the file complies with the policy",
+							Computed:    true,
+						},
+						"reasons": schema.ListAttribute{
+							Description: "Reasons the file does not comply with the policy",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+						"recipients": schema.ListAttribute{
+							Description: "Recipients the file is encrypted for",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+						"last_modified": schema.StringAttribute{
+							Description: "Time the file was last modified by sops, in RFC 3339 format",
+							Computed:    true,
+						},
+					},
+				},
+			},
+			"passed": schema.BoolAttribute{
+				Description: "Whether all files comply with the policy",
+				Computed:    true,
+			},
+			"id": schema.StringAttribute{
+				Description: "Unique identifier for this data source",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (d *policyCheckDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config policyCheckDataSourceModel
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	patterns := []string{"**"}
+	if !config.Patterns.IsNull() {
+		resp.Diagnostics.Append(config.Patterns.ElementsAs(ctx, &patterns, false)...)
+	}
+	var required []string
+	if !config.RequiredRecipients.IsNull() {
+		resp.Diagnostics.Append(config.RequiredRecipients.ElementsAs(ctx, &required, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var maxAge time.Duration
+	if !config.MaxAge.IsNull() {
+		var err error
+		maxAge, err = time.ParseDuration(config.MaxAge.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(tfpath.Root("max_age"), "Invalid max_age", err.Error())
+			return
+		}
+	}
+
+	directory := config.Directory.ValueString()
+	sopsConfig := config.SopsConfig.ValueString()
+	files, err := findFiles(directory, patterns)
+	if err != nil {
+		resp.Diagnostics.AddError("Error listing files", err.Error())
+		return
+	}
+	if len(files) == 0 {
+		resp.Diagnostics.AddWarning("No files matched", fmt.Sprintf("No files in %s matched the patterns %v", directory, patterns))
+	}
+
+	now := time.Now()
+	passed := true
+	results := make([]policyCheckFileModel, 0, len(files))
+	for _, f := range files {
+		filename := filepath.Join(directory, filepath.FromSlash(f))
+		if sopsConfig != "" && sameFile(filename, sopsConfig) {
+			continue
+		}
+		result := checkFilePolicy(filename, required, sopsConfig, maxAge, now)
+		result.Path = f
+		passed = passed && result.Passed
+		results = append(results, result)
+	}
+
+	l, listDiags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: policyCheckFileAttrTypes}, results)
+	resp.Diagnostics.Append(listDiags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config.Files = l
+	config.Passed = types.BoolValue(passed)
+	config.Id = types.StringValue(directory)
+
+	diags = resp.State.Set(ctx, config)
+	resp.Diagnostics.Append(diags...)
+}
+
+// checkFilePolicy inspects the sops metadata of a file and reports whether it
+// is encrypted for all required recipients, and was modified within maxAge.
+// Values in the file are never decrypted.
+func checkFilePolicy(filename string, required []string, sopsConfig string, maxAge time.Duration, now time.Time) policyCheckFileModel {
+	result := policyCheckFileModel{
+		Reasons:    []string{},
+		Recipients: []string{},
+	}
+	fail := func(format string, a ...interface{}) policyCheckFileModel {
+		result.Reasons = append(result.Reasons, fmt.Sprintf(format, a...))
+		return result
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fail("could not read file: %s", err)
+	}
+	format, err := resolveInputType(filename, content, types.StringNull())
+	if err != nil {
+		return fail("%s", err)
+	}
+	// Only the metadata is read, so the store configuration doesn't matter
+	tree, err := storeForInputType(nil, format).LoadEncryptedFile(content)
+	if err != nil {
+		return fail("not a sops-encrypted file: %s", err)
+	}
+
+	for _, group := range tree.Metadata.KeyGroups {
+		for _, key := range group {
+			result.Recipients = append(result.Recipients, key.ToString())
+		}
+	}
+	result.LastModified = tree.Metadata.LastModified.Format(time.RFC3339)
+
+	if sopsConfig != "" {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return fail("%s", err)
+		}
+		conf, err := config.LoadCreationRuleForFile(sopsConfig, abs, nil)
+		if err != nil {
+			return fail("could not load creation rule from %s: %s", sopsConfig, err)
+		}
+		if conf == nil {
+			return fail("%s has no creation rules", sopsConfig)
+		}
+		for _, group := range conf.KeyGroups {
+			for _, key := range group {
+				required = append(required, key.ToString())
+			}
+		}
+	}
+
+	for _, r := range required {
+		if !containsRecipient(result.Recipients, r) {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("not encrypted for recipient %s", r))
+		}
+	}
+
+	if maxAge > 0 {
+		// sops sets lastmodified whenever it encrypts the file, including when
+		// rotating the data key, unlike the created_at of master keys
+		if age := now.Sub(tree.Metadata.LastModified); age > maxAge {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("last modified %s ago, exceeding max_age of %s", age.Truncate(time.Second), maxAge))
+		}
+	}
+
+	result.Passed = len(result.Reasons) == 0
+	return result
+}
+
+func containsRecipient(recipients []string, recipient string) bool {
+	for _, r := range recipients {
+		if strings.EqualFold(strings.TrimSpace(r), strings.TrimSpace(recipient)) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
@@ -0,0 +1,105 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const configTestDataSourceSopsPolicyCheck_basic = `
+data "sops_policy_check" "test_basic" {
+  directory           = "%s/test-fixtures"
+  patterns            = ["basic.*", "nested.yaml"]
+  required_recipients = ["3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"]
+}`
+
+func TestDataSourceSopsPolicyCheck_basic(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsPolicyCheck_basic, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_basic", "passed", "true"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_basic", "files.#", "3"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_basic", "files.0.path", "basic.json"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_basic", "files.2.path", "nested.yaml"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsPolicyCheck_violations = `
+data "sops_policy_check" "test_violations" {
+  directory           = "%s/test-fixtures"
+  patterns            = ["basic.yaml", "basic-encrypt.yaml"]
+  required_recipients = ["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]
+  max_age             = "24h"
+}`
+
+func TestDataSourceSopsPolicyCheck_violations(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsPolicyCheck_violations, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_violations", "passed", "false"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_violations", "files.0.path", "basic-encrypt.yaml"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_violations", "files.0.passed", "false"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_violations", "files.1.path", "basic.yaml"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_violations", "files.1.reasons.#", "2"),
+					resource.TestCheckResourceAttr("data.sops_policy_check.test_violations", "files.1.recipients.0", "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"),
+				),
+			},
+		},
+	})
+}
+
+func TestCheckFilePolicy(t *testing.T) {
+	now := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	result := checkFilePolicy("test-fixtures/basic.yaml", []string{"3ce5cc7219d6597ce6488bf1bf36cd3d0749a11a"}, "", 30*24*time.Hour, now)
+	if !result.Passed {
+		t.Errorf("Expected basic.yaml to pass, got reasons %v", result.Reasons)
+	}
+
+	result = checkFilePolicy("test-fixtures/basic.yaml", []string{"some-other-key"}, "", time.Hour, now)
+	if result.Passed || len(result.Reasons) != 2 {
+		t.Errorf("Expected basic.yaml to fail with two reasons, got %v", result.Reasons)
+	}
+
+	result = checkFilePolicy("test-fixtures/basic-encrypt.yaml", nil, "", 0, now)
+	if result.Passed {
+		t.Error("Expected unencrypted basic-encrypt.yaml to fail")
+	}
+}
+
+func TestCheckFilePolicy_rotated(t *testing.T) {
+	filename := copyFixture(t, "basic.yaml")
+	if result := checkFilePolicy(filename, nil, "", time.Hour, time.Now()); result.Passed {
+		t.Fatal("Expected basic.yaml to exceed max_age before rotating its data key")
+	}
+	if _, err := rotateFile(context.Background(), filename, types.StringNull(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if result := checkFilePolicy(filename, nil, "", time.Hour, time.Now()); !result.Passed {
+		t.Errorf("Expected the rotated file to pass max_age, got reasons %v", result.Reasons)
+	}
+}
@@ -0,0 +1,152 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// toDynamic converts decoded data into a Terraform value of the matching type:
+// maps become objects, lists become tuples, and scalars keep their type.
+func toDynamic(ctx context.Context, v interface{}) (types.Dynamic, diag.Diagnostics) {
+	value, diags := toAttrValue(ctx, v)
+	if diags.HasError() {
+		return types.DynamicNull(), diags
+	}
+	return types.DynamicValue(value), diags
+}
+
+func toAttrValue(ctx context.Context, v interface{}) (attr.Value, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	switch typed := normalizeValue(v).(type) {
+	case map[string]interface{}:
+		attrTypes := make(map[string]attr.Type, len(typed))
+		attrs := make(map[string]attr.Value, len(typed))
+		for k, v := range typed {
+			value, ds := toAttrValue(ctx, v)
+			diags.Append(ds...)
+			if ds.HasError() {
+				continue
+			}
+			attrTypes[k] = value.Type(ctx)
+			attrs[k] = value
+		}
+		if diags.HasError() {
+			return nil, diags
+		}
+		value, ds := types.ObjectValue(attrTypes, attrs)
+		diags.Append(ds...)
+		return value, diags
+	case []interface{}:
+		elemTypes := make([]attr.Type, len(typed))
+		elems := make([]attr.Value, len(typed))
+		for i, v := range typed {
+			value, ds := toAttrValue(ctx, v)
+			diags.Append(ds...)
+			if ds.HasError() {
+				continue
+			}
+			elemTypes[i] = value.Type(ctx)
+			elems[i] = value
+		}
+		if diags.HasError() {
+			return nil, diags
+		}
+		value, ds := types.TupleValue(elemTypes, elems)
+		diags.Append(ds...)
+		return value, diags
+	case nil:
+		return types.StringNull(), diags
+	case string:
+		return types.StringValue(typed), diags
+	case bool:
+		return types.BoolValue(typed), diags
+	case int:
+		return types.NumberValue(new(big.Float).SetInt64(int64(typed))), diags
+	case int64:
+		return types.NumberValue(new(big.Float).SetInt64(typed)), diags
+	case uint64:
+		return types.NumberValue(new(big.Float).SetUint64(typed)), diags
+	case float64:
+		return types.NumberValue(big.NewFloat(typed)), diags
+	default:
+		return types.StringValue(fmt.Sprint(typed)), diags
+	}
+}
+
+// fromDynamic converts a Terraform value into generic maps, lists and
+// scalars, the inverse of toDynamic. Whole numbers become int64 and other
+// numbers float64.
+func fromDynamic(v types.Dynamic) (interface{}, error) {
+	if v.IsUnknown() {
+		return nil, fmt.Errorf("value is not known yet")
+	}
+	if v.IsNull() {
+		return nil, nil
+	}
+	return fromAttrValue(v.UnderlyingValue())
+}
+
+func fromAttrValue(v attr.Value) (interface{}, error) {
+	if v.IsUnknown() {
+		return nil, fmt.Errorf("value is not known yet")
+	}
+	if v.IsNull() {
+		return nil, nil
+	}
+	switch typed := v.(type) {
+	case types.Dynamic:
+		return fromDynamic(typed)
+	case types.Object:
+		return fromAttrMap(typed.Attributes())
+	case types.Map:
+		return fromAttrMap(typed.Elements())
+	case types.Tuple:
+		return fromAttrList(typed.Elements())
+	case types.List:
+		return fromAttrList(typed.Elements())
+	case types.Set:
+		return fromAttrList(typed.Elements())
+	case types.String:
+		return typed.ValueString(), nil
+	case types.Bool:
+		return typed.ValueBool(), nil
+	case types.Number:
+		f := typed.ValueBigFloat()
+		if i, acc := f.Int64(); acc == big.Exact {
+			return i, nil
+		}
+		n, _ := f.Float64()
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %s", v.Type(context.Background()))
+	}
+}
+
+func fromAttrMap(attrs map[string]attr.Value) (map[string]interface{}, error) {
+	ret := make(map[string]interface{}, len(attrs))
+	for k, v := range attrs {
+		value, err := fromAttrValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		ret[k] = value
+	}
+	return ret, nil
+}
+
+func fromAttrList(elems []attr.Value) ([]interface{}, error) {
+	ret := make([]interface{}, len(elems))
+	for i, v := range elems {
+		value, err := fromAttrValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", i, err)
+		}
+		ret[i] = value
+	}
+	return ret, nil
+}
@@ -14,7 +14,6 @@ import (
 	wordwrap "github.com/mitchellh/go-wordwrap"
 
 	mozillasops "github.com/getsops/sops/v3"
-	"github.com/getsops/sops/v3/logging"
 
 	//"github.com/getsops/sops/v3/azkv"
 	"github.com/getsops/sops/v3/cmd/sops/codes"
@@ -26,8 +25,6 @@ import (
 	"github.com/getsops/sops/v3/version"
 )
 
-var log = logging.NewLogger("SOPS")
-
 type EncryptOpts struct {
 	Cipher            mozillasops.Cipher
 	InputStore        mozillasops.Store
@@ -69,7 +66,7 @@ func ensureNoMetadata(branch mozillasops.TreeBranch) error {
 	return nil
 }
 
-func Encrypt(opts EncryptOpts, fileBytes []byte) (encryptedFile []byte, err error) {
+func Encrypt(ctx context.Context, opts EncryptOpts, fileBytes []byte) (encryptedFile []byte, err error) {
 	branches, err := opts.InputStore.LoadPlainFile(fileBytes)
 	if err != nil {
 		return nil, common.NewExitError(fmt.Sprintf("Error unmarshalling file: %s", err), codes.CouldNotReadInputFile)
@@ -97,10 +94,13 @@ func Encrypt(opts EncryptOpts, fileBytes []byte) (encryptedFile []byte, err erro
 		},
 		FilePath: path,
 	}
-	dataKey, errs := tree.GenerateDataKeyWithKeyServices(opts.KeyServices)
+	recorder := newKeyServiceRecorder(ctx, "encrypt")
+	dataKey, errs := tree.GenerateDataKeyWithKeyServices(recorder.wrap(opts.KeyServices))
 	if len(errs) > 0 {
-		err = fmt.Errorf("Could not generate data key: %s", errs)
-		return nil, err
+		if failure := recorder.failure(nil); len(failure.failed) > 0 {
+			return nil, failure
+		}
+		return nil, fmt.Errorf("Could not generate data key: %s", errs)
 	}
 
 	err = common.EncryptTree(common.EncryptTreeOpts{
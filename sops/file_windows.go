@@ -0,0 +1,16 @@
+//go:build windows
+
+package sops
+
+import "os"
+
+// fileOwner is unsupported on Windows, where files have no numeric owner
+func fileOwner(os.FileInfo) (uid, gid int, ok bool) {
+	return -1, -1, false
+}
+
+// lockDir is a no-op on Windows, where directories can't be locked. Writes
+// from this provider are still serialized by lockFile.
+func lockDir(string) (func(), error) {
+	return func() {}, nil
+}
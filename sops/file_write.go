@@ -0,0 +1,194 @@
+package sops
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// fileLocks holds a mutex for each file written by the provider, keyed by
+// resolved path, as Terraform applies resources in parallel
+var fileLocks sync.Map
+
+// maxSymlinks bounds the symlinks followed by resolveFile, as filepath does
+const maxSymlinks = 255
+
+// resolveFile returns the absolute path of the file filename refers to, with
+// all symlinks followed, so that aliases of a file share its lock and writes
+// replace the target of a symlink rather than the link. The file itself
+// needn't exist, nor the target of a dangling symlink.
+func resolveFile(filename string) (string, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; ; i++ {
+		info, err := os.Lstat(filename)
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			break
+		}
+		if i == maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", filename)
+		}
+		target, err := os.Readlink(filename)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(filename), target)
+		}
+		filename = target
+	}
+
+	dir, err := filepath.EvalSymlinks(filepath.Dir(filename))
+	if os.IsNotExist(err) {
+		return filename, nil
+	} else if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(filename)), nil
+}
+
+// lockFile serializes writes to filename. Resources of this provider are
+// serialized by an in-process mutex, and other processes following the same
+// protocol by an advisory lock on the directory of the file, which unlike
+// the file itself isn't replaced when writing. Both locks are those of the
+// file filename resolves to. The returned function releases both locks.
+func lockFile(filename string) (func(), error) {
+	resolved, err := resolveFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	m, _ := fileLocks.LoadOrStore(resolved, &sync.Mutex{})
+	mu := m.(*sync.Mutex)
+	mu.Lock()
+
+	unlockDir, err := lockDir(filepath.Dir(resolved))
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to lock the directory of %s: %w", filename, err)
+	}
+	return func() {
+		unlockDir()
+		mu.Unlock()
+	}, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and
+// renames it to filename, so readers never see a partially written file. If
+// filename is a symlink, its target is written.
+// perm is subject to the umask, as with os.WriteFile. The file is owned by
+// uid and gid, as with os.Chown, -1 keeping the default.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode, uid, gid int) error {
+	return writeFile(filename, data, perm, false, func(f *os.File) error {
+		if uid == -1 && gid == -1 {
+			return nil
+		}
+		return f.Chown(uid, gid)
+	})
+}
+
+// replaceFile atomically replaces the content of an existing file, keeping
+// its permissions and, where the process is allowed to, its owner
+func replaceFile(filename string, data []byte) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, data, info.Mode().Perm(), true, func(f *os.File) error {
+		if uid, gid, ok := fileOwner(info); ok {
+			// Only privileged processes can give files away, so a failure
+			// leaves the file owned by the current user
+			f.Chown(uid, gid)
+		}
+		return nil
+	})
+}
+
+func writeFile(filename string, data []byte, perm os.FileMode, exactPerm bool, chown func(*os.File) error) (err error) {
+	// The temporary file is created in the directory of the target, as
+	// renames don't cross file systems
+	if filename, err = resolveFile(filename); err != nil {
+		return err
+	}
+	dir, base := filepath.Split(filename)
+	var tmp *os.File
+	for i := 0; ; i++ {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", base, rand.Uint32()))
+		tmp, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) || i == 10 {
+			return err
+		}
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if exactPerm {
+		if err = tmp.Chmod(perm); err != nil {
+			return err
+		}
+	}
+	if err = chown(tmp); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// lookupOwner resolves a user and group, given as names or numeric IDs, to
+// the IDs taken by os.Chown. Empty values resolve to -1.
+func lookupOwner(owner, group string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if owner != "" {
+		id := owner
+		if _, err := strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return 0, 0, err
+			}
+			id = u.Uid
+		}
+		if uid, err = strconv.Atoi(id); err != nil {
+			return 0, 0, fmt.Errorf("user %s has no numeric ID", owner)
+		}
+	}
+	if group != "" {
+		id := group
+		if _, err := strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, err
+			}
+			id = g.Gid
+		}
+		if gid, err = strconv.Atoi(id); err != nil {
+			return 0, 0, fmt.Errorf("group %s has no numeric ID", group)
+		}
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,175 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "secrets.yaml")
+
+	if err := writeFileAtomic(filename, []byte("first"), 0600, -1, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filename, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFile(filename, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "second" {
+		t.Errorf("Expected the content to be replaced, got %q", content)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected the file mode to be kept, got %s", info.Mode())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %v", entries)
+	}
+}
+
+func TestWriteFileAtomic_symlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Creating symlinks requires privileges on Windows")
+	}
+	targetDir, linkDir := t.TempDir(), t.TempDir()
+	target := filepath.Join(targetDir, "secrets.yaml")
+	if err := os.WriteFile(target, []byte("first"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(linkDir, "secrets.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	dangling := filepath.Join(linkDir, "new.yaml")
+	if err := os.Symlink(filepath.Join("..", filepath.Base(targetDir), "new.yaml"), dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	resolvedLink, err := resolveFile(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolvedTarget, err := resolveFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolvedLink != resolvedTarget {
+		t.Errorf("Expected %s to resolve to %s, got %s", link, resolvedTarget, resolvedLink)
+	}
+
+	if err := replaceFile(link, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(dangling, []byte("third"), 0600, -1, -1); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{target: "second", filepath.Join(targetDir, "new.yaml"): "third"} {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected %s to contain %q, got %q", name, expected, content)
+		}
+	}
+	for _, name := range []string{link, dangling} {
+		info, err := os.Lstat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("Expected %s to remain a symlink, got %s", name, info.Mode())
+		}
+	}
+	entries, err := os.ReadDir(linkDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected no files to be created next to the links, got %v", entries)
+	}
+}
+
+func TestLockFile_concurrentEdits(t *testing.T) {
+	filename := copyFixture(t, "nested.yaml")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- setFileEntry(context.Background(), filename, types.StringNull(), nil, fmt.Sprintf("key%d", i), fmt.Sprint(i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := readFixtureData(t, filename)
+	for i := 0; i < 8; i++ {
+		if v := data[fmt.Sprintf("key%d", i)]; v != fmt.Sprint(i) {
+			t.Errorf("Expected key%d to be %d, got %q", i, i, v)
+		}
+	}
+	if data["db.password"] != "bar" {
+		t.Errorf("Expected the other values to be kept, got %v", data)
+	}
+}
+
+func TestLookupOwner(t *testing.T) {
+	uid, gid, err := lookupOwner("", "")
+	if err != nil || uid != -1 || gid != -1 {
+		t.Errorf("Expected -1, -1 for unset owner and group, got %d, %d, %v", uid, gid, err)
+	}
+
+	uid, gid, err = lookupOwner("0", "0")
+	if err != nil || uid != 0 || gid != 0 {
+		t.Errorf("Expected numeric IDs to be kept, got %d, %d, %v", uid, gid, err)
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	uid, _, err = lookupOwner(current.Username, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strconv.Itoa(uid) != current.Uid {
+		t.Errorf("Expected uid %s for %s, got %d", current.Uid, current.Username, uid)
+	}
+
+	if _, _, err := lookupOwner("no-such-user-for-sops", ""); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+}
@@ -1,6 +1,16 @@
 package sops
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+const defaultKeySeparator = "."
 
 // flatten flattens the nested struct.
 //
@@ -8,59 +18,117 @@ import "fmt"
 // e.g. {"a": {"b":"c"}} => {"a.b":"c"}
 // or {"a": {"b":[1,2]}} => {"a.b.0":1, "a.b.1": 2}
 func flatten(data map[string]interface{}) map[string]string {
+	return newFlattener(defaultKeySeparator, false).flatten(data)
+}
+
+// flattener flattens nested structs, joining keys with a configurable
+// separator. Paths that flatten to the same key are recorded as collisions.
+type flattener struct {
+	separator  string
+	escapeKeys bool
+	collisions []string
+}
+
+// newFlattener returns a flattener joining keys with separator. If escapeKeys
+// is set, occurrences of the separator (and of the escape character) in keys
+// are escaped with a backslash, so that distinct paths never collide.
+// e.g. {"a.b": "c", "a": {"b": "d"}} => {"a\.b": "c", "a.b": "d"}
+func newFlattener(separator string, escapeKeys bool) *flattener {
+	return &flattener{separator: separator, escapeKeys: escapeKeys}
+}
+
+func (f *flattener) flatten(data map[string]interface{}) map[string]string {
 	ret := make(map[string]string)
-	for k, v := range data {
-		switch typed := v.(type) {
-		case map[interface{}]interface{}:
-			for fk, fv := range flatten(convertMap(typed)) {
-				ret[fmt.Sprintf("%s.%s", k, fk)] = fv
-			}
-		case map[string]interface{}:
-			for fk, fv := range flatten(typed) {
-				ret[fmt.Sprintf("%s.%s", k, fk)] = fv
-			}
-		case []interface{}:
-			for fk, fv := range flattenSlice(typed) {
-				ret[fmt.Sprintf("%s.%s", k, fk)] = fv
-			}
-		case nil:
-			ret[k] = "null"
-		default:
-			ret[k] = fmt.Sprint(typed)
+	f.flattenInto(ret, nil, data)
+	return ret
+}
+
+func (f *flattener) flattenInto(ret map[string]string, path []string, v interface{}) {
+	switch typed := normalizeValue(v).(type) {
+	case map[string]interface{}:
+		// Sorting the keys makes the result deterministic if keys collide
+		keys := make([]string, 0, len(typed))
+		for k := range typed {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			f.flattenInto(ret, append(path[:len(path):len(path)], f.escape(k)), typed[k])
 		}
+	case []interface{}:
+		for idx, v := range typed {
+			f.flattenInto(ret, append(path[:len(path):len(path)], strconv.Itoa(idx)), v)
+		}
+	case nil:
+		f.set(ret, path, "null")
+	default:
+		f.set(ret, path, fmt.Sprint(typed))
 	}
-	return ret
 }
 
-func flattenSlice(data []interface{}) map[string]string {
-	ret := make(map[string]string)
-	for idx, v := range data {
-		switch typed := v.(type) {
-		case map[interface{}]interface{}:
-			for fk, fv := range flatten(convertMap(typed)) {
-				ret[fmt.Sprintf("%d.%s", idx, fk)] = fv
-			}
-		case map[string]interface{}:
-			for fk, fv := range flatten(typed) {
-				ret[fmt.Sprintf("%d.%s", idx, fk)] = fv
-			}
-		case []interface{}:
-			for fk, fv := range flattenSlice(typed) {
-				ret[fmt.Sprintf("%d.%s", idx, fk)] = fv
+func (f *flattener) set(ret map[string]string, path []string, value string) {
+	key := strings.Join(path, f.separator)
+	if _, ok := ret[key]; ok {
+		f.collisions = append(f.collisions, key)
+	}
+	ret[key] = value
+}
+
+func (f *flattener) escape(key string) string {
+	if !f.escapeKeys {
+		return key
+	}
+	key = strings.ReplaceAll(key, `\`, `\\`)
+	return strings.ReplaceAll(key, f.separator, `\`+f.separator)
+}
+
+// splitKeyPath splits a flattened key into the keys it was joined from. If
+// escaped is set, a backslash escapes the next character, as keys are
+// escaped by escapeKeys.
+func splitKeyPath(key, separator string, escaped bool) ([]string, error) {
+	var (
+		keys    []string
+		current strings.Builder
+	)
+	for i := 0; i < len(key); i++ {
+		switch {
+		case escaped && key[i] == '\\':
+			if i == len(key)-1 {
+				return nil, fmt.Errorf("key %q ends with an escape character", key)
 			}
-		case nil:
-			ret[fmt.Sprint(idx)] = "null"
+			i++
+			current.WriteByte(key[i])
+		case strings.HasPrefix(key[i:], separator):
+			keys = append(keys, current.String())
+			current.Reset()
+			i += len(separator) - 1
 		default:
-			ret[fmt.Sprint(idx)] = fmt.Sprint(typed)
+			current.WriteByte(key[i])
 		}
 	}
-	return ret
+	keys = append(keys, current.String())
+
+	for _, k := range keys {
+		if k == "" {
+			return nil, fmt.Errorf("key %q contains an empty key", key)
+		}
+	}
+	return keys, nil
 }
 
-func convertMap(originalMap map[interface{}]interface{}) map[string]interface{} {
-	convertedMap := map[string]interface{}{}
-	for key, value := range originalMap {
-		convertedMap[key.(string)] = value
+// addCollisionWarnings adds a warning for each key that several paths were
+// flattened to. Only the keys are included, never the values.
+func addCollisionWarnings(diags *diag.Diagnostics, collisions []string) {
+	seen := make(map[string]bool, len(collisions))
+	for _, key := range collisions {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		diags.AddAttributeWarning(
+			tfpath.Root("data"),
+			"Colliding keys in data",
+			fmt.Sprintf("Several paths flatten to the key %q, only one of their values is kept. Set escape_keys, or choose a key_separator that doesn't occur in keys, to keep all values.", key),
+		)
 	}
-	return convertedMap
 }
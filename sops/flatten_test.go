@@ -87,3 +87,67 @@ func TestFlattening(t *testing.T) {
 		})
 	}
 }
+
+func TestFlatteningWithSeparator(t *testing.T) {
+	input := map[string]interface{}{
+		"a.b": "dotted",
+		"a":   map[string]interface{}{"b": "nested", "c": []interface{}{1}},
+	}
+
+	tc := []struct {
+		name       string
+		flattener  *flattener
+		expected   map[string]string
+		collisions []string
+	}{
+		{
+			name:       "default separator collides",
+			flattener:  newFlattener(".", false),
+			expected:   map[string]string{"a.b": "dotted", "a.c.0": "1"},
+			collisions: []string{"a.b"},
+		},
+		{
+			name:      "custom separator",
+			flattener: newFlattener("/", false),
+			expected:  map[string]string{"a.b": "dotted", "a/b": "nested", "a/c/0": "1"},
+		},
+		{
+			name:      "escaped keys",
+			flattener: newFlattener(".", true),
+			expected:  map[string]string{`a\.b`: "dotted", "a.b": "nested", "a.c.0": "1"},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			output := c.flattener.flatten(input)
+			if !reflect.DeepEqual(c.expected, output) {
+				t.Errorf("Unexpected flattening output, expected %v, got %v", c.expected, output)
+			}
+			if !reflect.DeepEqual(c.collisions, c.flattener.collisions) {
+				t.Errorf("Unexpected collisions, expected %v, got %v", c.collisions, c.flattener.collisions)
+			}
+		})
+	}
+}
+
+func TestSplitKeyPath(t *testing.T) {
+	for _, tc := range []struct {
+		key, separator string
+		escaped        bool
+		expected       []string
+	}{
+		{"db.password", ".", false, []string{"db", "password"}},
+		{"db__password", "__", false, []string{"db", "password"}},
+		{`a\.b.c`, ".", true, []string{"a.b", "c"}},
+		{`a\b.c`, ".", false, []string{`a\b`, "c"}},
+	} {
+		actual, err := splitKeyPath(tc.key, tc.separator, tc.escaped)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tc.key, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.expected, actual) {
+			t.Errorf("%q: expected %q, got %q", tc.key, tc.expected, actual)
+		}
+	}
+}
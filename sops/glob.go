@@ -0,0 +1,71 @@
+package sops
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// findFiles walks dir and returns the paths of all regular files matching at
+// least one of the patterns. Returned paths are relative to dir and use forward
+// slashes, regardless of platform.
+func findFiles(dir string, patterns []string) ([]string, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, pattern := range patterns {
+			if matchGlob(pattern, rel) {
+				files = append(files, rel)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// matchGlob reports whether name matches pattern. Patterns use the syntax of
+// path.Match, extended so that a "**" segment matches any number of directories.
+// e.g. "**/*.enc.yaml" matches both "a.enc.yaml" and "x/y/a.enc.yaml"
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,57 @@
+package sops
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tc := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.yaml", "a.yaml", true},
+		{"*.yaml", "x/a.yaml", false},
+		{"x/*.yaml", "x/a.yaml", true},
+		{"**/*.yaml", "a.yaml", true},
+		{"**/*.yaml", "x/y/a.yaml", true},
+		{"x/**", "x/y/a.yaml", true},
+		{"x/**/a.yaml", "x/a.yaml", true},
+		{"x/**/a.yaml", "z/a.yaml", false},
+		{"*.enc.*", "secrets.enc.json", true},
+	}
+	for _, c := range tc {
+		if got := matchGlob(c.pattern, c.name); got != c.expected {
+			t.Errorf("matchGlob(%q, %q) = %v, expected %v", c.pattern, c.name, got, c.expected)
+		}
+	}
+}
+
+func TestFindFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"a.yaml", "b.json", "nested/c.yaml", "nested/deeper/d.yaml"} {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := findFiles(dir, []string{"**/*.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a.yaml", "nested/c.yaml", "nested/deeper/d.yaml"}
+	if !reflect.DeepEqual(expected, files) {
+		t.Errorf("Unexpected files, expected %v, got %v", expected, files)
+	}
+
+	if _, err := findFiles(dir, []string{"["}); err == nil {
+		t.Error("Expected an error for a malformed pattern")
+	}
+}
@@ -0,0 +1,25 @@
+package sops
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// hashData returns the SHA-256 of each value in data, hex encoded. If salt
+// is set, values are hashed with HMAC-SHA256 keyed with salt instead, so
+// values that are easy to guess can't be recovered from their hashes.
+func hashData(data map[string]string, salt string) map[string]string {
+	hashes := make(map[string]string, len(data))
+	for k, v := range data {
+		if salt == "" {
+			sum := sha256.Sum256([]byte(v))
+			hashes[k] = hex.EncodeToString(sum[:])
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write([]byte(v))
+		hashes[k] = hex.EncodeToString(mac.Sum(nil))
+	}
+	return hashes
+}
@@ -0,0 +1,21 @@
+package sops
+
+import (
+	"testing"
+)
+
+func TestHashData(t *testing.T) {
+	data := map[string]string{"password": "foo"}
+
+	plain := hashData(data, "")
+	// echo -n foo | sha256sum
+	if expected := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"; plain["password"] != expected {
+		t.Errorf("Expected %s, got %s", expected, plain["password"])
+	}
+
+	salted := hashData(data, "salt")
+	// echo -n foo | openssl dgst -sha256 -hmac salt
+	if expected := "6a9534d88e984dfcea835f190147b72b3f647fdcc2409e5b8be8b331ec7fe8a5"; salted["password"] != expected {
+		t.Errorf("Expected %s, got %s", expected, salted["password"])
+	}
+}
@@ -6,24 +6,208 @@ import (
 	"strings"
 )
 
+// Options enable parsing dotenv data written by hand rather than emitted by
+// sops. The zero value parses data the way sops' dotenv store does.
+type Options struct {
+	// Quotes parses values the way shells do: whitespace around keys and
+	// values is ignored, keys may be prefixed with "export ", and values are
+	// unquoted or end at an inline comment. See UnmarshalWithOptions.
+	Quotes bool
+}
+
+// Unmarshal parses dotenv data as emitted by sops' dotenv store, which is how
+// decrypted dotenv files are read.
+//
+// Each line holds a KEY=value pair, split at the first =, and lines starting
+// with # are comments. Values are taken verbatim, except for \n, which is
+// expanded to a newline, as sops escapes newlines that way.
 func Unmarshal(in []byte, out *map[string]interface{}) error {
+	return UnmarshalWithOptions(in, out, Options{})
+}
+
+// UnmarshalWithOptions parses dotenv data. Without opts.Quotes, it is
+// Unmarshal. With opts.Quotes, each line holds a KEY=value pair, optionally
+// prefixed with "export ". Whitespace around keys and values is ignored, and
+// lines starting with # are comments. Values can be:
+//
+//   - unquoted, ending at the end of the line or at an inline comment ( #).
+//     \n is expanded to a newline.
+//   - single-quoted, which are taken literally and may span several lines.
+//   - double-quoted, which may span several lines and expand the escape
+//     sequences \n, \r, \t, \", \\ and \$.
+func UnmarshalWithOptions(in []byte, out *map[string]interface{}, opts Options) error {
 	if *out == nil {
 		*out = make(map[string]interface{})
 	}
-	for _, line := range bytes.Split(in, []byte("\n")) {
-		if len(line) == 0 {
+	if !opts.Quotes {
+		return unmarshalSops(in, *out)
+	}
+
+	p := parser{src: string(bytes.ReplaceAll(in, []byte("\r\n"), []byte("\n"))), line: 1}
+	for {
+		key, value, ok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		(*out)[key] = value
+	}
+}
+
+// unmarshalSops parses data like the LoadPlainFile method of sops' dotenv
+// store, the inverse of its EmitPlainFile
+func unmarshalSops(in []byte, out map[string]interface{}) error {
+	for i, line := range bytes.Split(in, []byte("\n")) {
+		if len(line) == 0 || line[0] == '#' {
 			continue
 		}
-		if line[0] == '#' {
+		pos := bytes.IndexByte(line, '=')
+		if pos == -1 {
+			return &SyntaxError{Line: i + 1, Msg: "expected KEY=value"}
+		}
+		out[string(line[:pos])] = strings.ReplaceAll(string(line[pos+1:]), `\n`, "\n")
+	}
+	return nil
+}
+
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// SyntaxError describes a malformed line. It deliberately carries no content
+// from the input, which may be a decrypted secret.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid dotenv input on line %d: %s", e.Line, e.Msg)
+}
+
+func (p *parser) errorf(format string, a ...interface{}) error {
+	return &SyntaxError{Line: p.line, Msg: fmt.Sprintf(format, a...)}
+}
+
+// next returns the next key and value, or ok = false at the end of the input
+func (p *parser) next() (key, value string, ok bool, err error) {
+	for {
+		p.skip(" \t")
+		if p.pos >= len(p.src) {
+			return "", "", false, nil
+		}
+		switch p.src[p.pos] {
+		case '\n':
+			p.pos++
+			p.line++
+			continue
+		case '#':
+			p.skipLine()
 			continue
-		} else {
-			pos := bytes.Index(line, []byte("="))
-			if pos == -1 {
-				return fmt.Errorf("invalid dotenv input line: %s", line)
+		}
+		break
+	}
+
+	line := p.src[p.pos:]
+	if end := strings.IndexByte(line, '\n'); end != -1 {
+		line = line[:end]
+	}
+	eq := strings.IndexByte(line, '=')
+	if eq == -1 {
+		return "", "", false, p.errorf("expected KEY=value")
+	}
+	key = strings.TrimSpace(line[:eq])
+	if strings.HasPrefix(key, "export ") || strings.HasPrefix(key, "export\t") {
+		key = strings.TrimSpace(key[len("export"):])
+	}
+	if key == "" {
+		return "", "", false, p.errorf("missing key")
+	}
+	if strings.ContainsAny(key, " \t") {
+		return "", "", false, p.errorf("key contains whitespace")
+	}
+	p.pos += eq + 1
+	p.skip(" \t")
+
+	if p.pos < len(p.src) && (p.src[p.pos] == '\'' || p.src[p.pos] == '"') {
+		value, err = p.quoted(p.src[p.pos])
+		if err != nil {
+			return "", "", false, err
+		}
+		p.skip(" \t")
+		if p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '#' {
+			return "", "", false, p.errorf("unexpected characters after quoted value of %s", key)
+		}
+		p.skipLine()
+		return key, value, true, nil
+	}
+
+	return key, p.unquoted(), true, nil
+}
+
+func (p *parser) unquoted() string {
+	start := p.pos
+	end := start
+	for ; end < len(p.src) && p.src[end] != '\n'; end++ {
+		if p.src[end] == '#' && end > start && (p.src[end-1] == ' ' || p.src[end-1] == '\t') {
+			break
+		}
+	}
+	value := strings.TrimRight(p.src[start:end], " \t")
+	p.pos = end
+	p.skipLine()
+	return strings.ReplaceAll(value, `\n`, "\n")
+}
+
+func (p *parser) quoted(quote byte) (string, error) {
+	startLine := p.line
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\n':
+			p.line++
+			b.WriteByte(c)
+		case c == '\\' && quote == '"' && p.pos < len(p.src):
+			escaped := p.src[p.pos]
+			p.pos++
+			switch escaped {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(escaped)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(escaped)
 			}
-			(*out)[string(line[:pos])] = strings.Replace(string(line[pos+1:]), "\\n", "\n", -1)
+		default:
+			b.WriteByte(c)
 		}
 	}
+	p.line = startLine
+	return "", p.errorf("unterminated quoted value")
+}
 
-	return nil
+func (p *parser) skip(chars string) {
+	for p.pos < len(p.src) && strings.IndexByte(chars, p.src[p.pos]) != -1 {
+		p.pos++
+	}
+}
+
+func (p *parser) skipLine() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
 }
@@ -1,8 +1,15 @@
 package dotenv
 
 import (
+	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
+
+	"github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/config"
+	sopsdotenv "github.com/getsops/sops/v3/stores/dotenv"
 )
 
 func TestUnmarshal(t *testing.T) {
@@ -20,3 +27,234 @@ password=P@ssw0rd`)
 		t.Errorf("Unexpected output, expected %v, got %v", expectedOutput, data)
 	}
 }
+
+func TestUnmarshal_sopsValues(t *testing.T) {
+	tc := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{"leading quote", "A='abc\n", map[string]interface{}{"A": "'abc"}},
+		{"inline comment", "A=a #b\n", map[string]interface{}{"A": "a #b"}},
+		{"whitespace", "A= x \n", map[string]interface{}{"A": " x "}},
+		{"quotes", `A="x"` + "\n", map[string]interface{}{"A": `"x"`}},
+		{"escaped newline", `A=x\ny`, map[string]interface{}{"A": "x\ny"}},
+		{"split at the first =", "A=b=c\n#B=d\n\n", map[string]interface{}{"A": "b=c"}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := Unmarshal([]byte(c.input), &data); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, data) {
+				t.Errorf("Unexpected output, expected %q, got %q", c.expected, data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_quotes(t *testing.T) {
+	tc := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "export prefix and whitespace around =",
+			input:    "export FOO = bar\n\tBAZ=\tqux  \n",
+			expected: map[string]interface{}{"FOO": "bar", "BAZ": "qux"},
+		},
+		{
+			name:     "quotes are removed",
+			input:    `SINGLE='a "b" c'` + "\n" + `DOUBLE="a 'b' c"`,
+			expected: map[string]interface{}{"SINGLE": `a "b" c`, "DOUBLE": "a 'b' c"},
+		},
+		{
+			name:     "inline comments",
+			input:    "A=foo # comment\nB=foo#bar\nC=\"foo # bar\" # comment\nD=",
+			expected: map[string]interface{}{"A": "foo", "B": "foo#bar", "C": "foo # bar", "D": ""},
+		},
+		{
+			name:     "CRLF line endings",
+			input:    "A=foo\r\nB=\"bar\"\r\n",
+			expected: map[string]interface{}{"A": "foo", "B": "bar"},
+		},
+		{
+			name:     "escape sequences",
+			input:    `A=foo\nbar` + "\n" + `B="foo\nbar\t\"baz\"\\"` + "\n" + `C='foo\nbar'`,
+			expected: map[string]interface{}{"A": "foo\nbar", "B": "foo\nbar\t\"baz\"\\", "C": `foo\nbar`},
+		},
+		{
+			name:     "multi-line quoted values",
+			input:    "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nNEXT='x\ny'\n",
+			expected: map[string]interface{}{"KEY": "-----BEGIN KEY-----\nabc\n-----END KEY-----", "NEXT": "x\ny"},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := UnmarshalWithOptions([]byte(c.input), &data, Options{Quotes: true}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, data) {
+				t.Errorf("Unexpected output, expected %q, got %q", c.expected, data)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	tc := []struct {
+		name   string
+		input  string
+		quotes bool
+		line   int
+	}{
+		{"missing =", "A=b\nsecret-value", false, 2},
+		{"missing = with quotes", "A=b\nsecret-value", true, 2},
+		{"missing key", "\n\n=secret-value", true, 3},
+		{"unterminated quote", "A=b\nB=\"secret\nvalue", true, 2},
+		{"trailing characters", "A='secret' value", true, 1},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			err := UnmarshalWithOptions([]byte(c.input), &data, Options{Quotes: c.quotes})
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Line != c.line {
+				t.Errorf("Expected error on line %d, got %d", c.line, syntaxErr.Line)
+			}
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("Error message contains input data: %s", err)
+			}
+		})
+	}
+}
+
+// sopsValue is any string sops' dotenv store can emit
+type sopsValue string
+
+const sopsValueChars = "abcXYZ019 =#'\"\\\n\t$!-_/+"
+
+func (sopsValue) Generate(r *rand.Rand, size int) reflect.Value {
+	b := make([]byte, r.Intn(size+1))
+	for i := range b {
+		b[i] = sopsValueChars[r.Intn(len(sopsValueChars))]
+	}
+	return reflect.ValueOf(sopsValue(b))
+}
+
+type sopsKey string
+
+const sopsKeyChars = "ABCXYZabc_019"
+
+func (sopsKey) Generate(r *rand.Rand, size int) reflect.Value {
+	b := make([]byte, r.Intn(size)+1)
+	for i := range b {
+		b[i] = sopsKeyChars[r.Intn(len(sopsKeyChars))]
+	}
+	return reflect.ValueOf(sopsKey(b))
+}
+
+// Values emitted by sops' dotenv store, as done when decrypting a file, must
+// parse back to the values in the sops tree.
+func TestUnmarshal_sopsEmitRoundTrip(t *testing.T) {
+	store := sopsdotenv.NewStore(&config.NewStoresConfig().Dotenv)
+	f := func(values map[sopsKey]sopsValue) bool {
+		var branch sops.TreeBranch
+		expected := map[string]interface{}{}
+		for k, v := range values {
+			branch = append(branch, sops.TreeItem{Key: string(k), Value: string(v)})
+			// sops escapes newlines as \n, but not backslashes, so a \n in a
+			// value reads back as a newline, with sops as well
+			expected[string(k)] = strings.ReplaceAll(string(v), `\n`, "\n")
+		}
+		emitted, err := store.EmitPlainFile(sops.TreeBranches{branch})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var data map[string]interface{}
+		if err := Unmarshal(emitted, &data); err != nil {
+			t.Logf("failed to parse %q: %s", emitted, err)
+			return false
+		}
+		if !reflect.DeepEqual(expected, data) {
+			t.Logf("emitted %q, expected %q, got %q", emitted, expected, data)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// dotenvLine is a line of dotenv content as written by a user
+type dotenvLine string
+
+func (dotenvLine) Generate(r *rand.Rand, size int) reflect.Value {
+	key := string(sopsKey("").Generate(r, size).Interface().(sopsKey))
+	value := string(sopsValue("").Generate(r, size).Interface().(sopsValue))
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	switch r.Intn(6) {
+	case 0:
+		return reflect.ValueOf(dotenvLine("# " + value))
+	case 1:
+		return reflect.ValueOf(dotenvLine("export " + key + "=" + value))
+	case 2:
+		return reflect.ValueOf(dotenvLine(key + " = " + value + " # comment"))
+	case 3:
+		return reflect.ValueOf(dotenvLine(key + "='" + strings.ReplaceAll(value, "'", "") + "'"))
+	case 4:
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+		return reflect.ValueOf(dotenvLine(key + `="` + value + `"`))
+	default:
+		return reflect.ValueOf(dotenvLine(key + "=" + value))
+	}
+}
+
+// Content written with sops_file passes through sops' dotenv store before
+// being encrypted. Decrypting it must result in the same data as parsing the
+// original content.
+func TestUnmarshal_sopsContentRoundTrip(t *testing.T) {
+	store := sopsdotenv.NewStore(&config.NewStoresConfig().Dotenv)
+	f := func(lines []dotenvLine) bool {
+		var content strings.Builder
+		for _, l := range lines {
+			content.WriteString(string(l) + "\n")
+		}
+
+		var expected map[string]interface{}
+		if err := Unmarshal([]byte(content.String()), &expected); err != nil {
+			t.Logf("failed to parse %q: %s", content.String(), err)
+			return false
+		}
+
+		branches, err := store.LoadPlainFile([]byte(content.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		emitted, err := store.EmitPlainFile(branches)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data map[string]interface{}
+		if err := Unmarshal(emitted, &data); err != nil {
+			t.Logf("failed to parse %q: %s", emitted, err)
+			return false
+		}
+		if !reflect.DeepEqual(expected, data) {
+			t.Logf("content %q, expected %q, got %q", content.String(), expected, data)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
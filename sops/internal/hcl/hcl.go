@@ -0,0 +1,102 @@
+package hcl
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Unmarshal parses HCL attribute syntax, as used in .tfvars files. Values must
+// be constant expressions, and are decoded to strings, numbers, bools, lists
+// and maps.
+func Unmarshal(in []byte, out *map[string]interface{}) error {
+	file, diags := hclsyntax.ParseConfig(in, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return diagsError(diags)
+	}
+	body := file.Body.(*hclsyntax.Body)
+	if len(body.Blocks) > 0 {
+		return &SyntaxError{Line: body.Blocks[0].TypeRange.Start.Line, Msg: "blocks are not supported, only attributes"}
+	}
+	attrs, diags := body.JustAttributes()
+	if diags.HasErrors() {
+		return diagsError(diags)
+	}
+
+	if *out == nil {
+		*out = make(map[string]interface{})
+	}
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return diagsError(diags)
+		}
+		(*out)[name] = decodeValue(value)
+	}
+	return nil
+}
+
+// SyntaxError describes malformed input. Unlike the HCL diagnostics it is
+// built from, it carries no content from the input, which may be a decrypted
+// secret.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid HCL input on line %d: %s", e.Line, e.Msg)
+}
+
+func diagsError(diags hcl.Diagnostics) error {
+	for _, d := range diags {
+		if d.Severity != hcl.DiagError {
+			continue
+		}
+		line := 0
+		if d.Subject != nil {
+			line = d.Subject.Start.Line
+		}
+		return &SyntaxError{Line: line, Msg: d.Summary}
+	}
+	return nil
+}
+
+func decodeValue(v cty.Value) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return v.AsString()
+	case t == cty.Bool:
+		return v.True()
+	case t == cty.Number:
+		f := v.AsBigFloat()
+		if i, acc := f.Int64(); acc == big.Exact {
+			return i
+		}
+		n, _ := f.Float64()
+		return n
+	case t.IsListType() || t.IsTupleType() || t.IsSetType():
+		ret := make([]interface{}, 0, v.LengthInt())
+		for it := v.ElementIterator(); it.Next(); {
+			_, e := it.Element()
+			ret = append(ret, decodeValue(e))
+		}
+		return ret
+	case t.IsMapType() || t.IsObjectType():
+		ret := make(map[string]interface{}, v.LengthInt())
+		for it := v.ElementIterator(); it.Next(); {
+			k, e := it.Element()
+			ret[k.AsString()] = decodeValue(e)
+		}
+		return ret
+	default:
+		return v.GoString()
+	}
+}
@@ -0,0 +1,74 @@
+package hcl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	input := []byte(`# Comment!
+password = "P@ssw0rd"
+port     = 5432
+ratio    = 0.5
+enabled  = true
+nothing  = null
+hosts    = ["a.example", "b.example"]
+db = {
+  user = "foo"
+  "read-only" = false
+}
+banner = <<EOT
+hello
+EOT
+`)
+	expectedOutput := map[string]interface{}{
+		"password": "P@ssw0rd",
+		"port":     int64(5432),
+		"ratio":    0.5,
+		"enabled":  true,
+		"nothing":  nil,
+		"hosts":    []interface{}{"a.example", "b.example"},
+		"db": map[string]interface{}{
+			"user":      "foo",
+			"read-only": false,
+		},
+		"banner": "hello\n",
+	}
+	var data map[string]interface{}
+	err := Unmarshal(input, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expectedOutput, data) {
+		t.Errorf("Unexpected output, expected %v, got %v", expectedOutput, data)
+	}
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	tc := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"missing value", "a = 1\nsecret =", 2},
+		{"blocks", "a = 1\n\nsecret {\n}", 3},
+		{"references", "a = var.secret", 1},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			err := Unmarshal([]byte(c.input), &data)
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Line != c.line {
+				t.Errorf("Expected error on line %d, got %d", c.line, syntaxErr.Line)
+			}
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("Error message contains input data: %s", err)
+			}
+		})
+	}
+}
@@ -1,13 +1,31 @@
 package ini
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"gopkg.in/ini.v1"
 )
 
+// Options enable richer decoding of INI data. The zero value decodes every
+// section as a single level of string values.
+type Options struct {
+	// NestedSections decodes dotted section names such as [db.primary] as
+	// nested maps
+	NestedSections bool
+	// TypedValues decodes values that are booleans or numbers as such
+	TypedValues bool
+}
+
 func Unmarshal(in []byte, out *map[string]interface{}) error {
-	f, err := ini.Load(in)
+	return UnmarshalWithOptions(in, out, Options{})
+}
+
+func UnmarshalWithOptions(in []byte, out *map[string]interface{}, opts Options) error {
+	f, err := ini.LoadSources(ini.LoadOptions{}, in)
 	if err != nil {
-		return err
+		return syntaxError(in, err)
 	}
 
 	if *out == nil {
@@ -20,15 +38,102 @@ func Unmarshal(in []byte, out *map[string]interface{}) error {
 		// map, not under a default subkey
 		if s.Name() == ini.DefaultSection {
 			m = *out
+		} else if opts.NestedSections {
+			m, err = sectionMap(*out, strings.Split(s.Name(), "."))
+			if err != nil {
+				return err
+			}
 		} else {
 			m = make(map[string]interface{})
 			(*out)[s.Name()] = m
 		}
 
 		for _, k := range s.Keys() {
-			m[k.Name()] = k.Value()
+			if _, ok := m[k.Name()].(map[string]interface{}); ok {
+				return fmt.Errorf("key %q in section [%s] conflicts with a section of the same name", k.Name(), s.Name())
+			}
+			m[k.Name()] = decodeValue(k.Value(), opts)
 		}
 	}
 
 	return nil
 }
+
+// SyntaxError describes malformed input. Unlike the errors of the INI parser
+// it is built from, it carries no content from the input, which may be a
+// decrypted secret.
+type SyntaxError struct {
+	// Line is 0 if the line of the error is unknown
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("invalid INI input: %s", e.Msg)
+	}
+	return fmt.Sprintf("invalid INI input on line %d: %s", e.Line, e.Msg)
+}
+
+// syntaxError converts an error of the INI parser, which quotes the offending
+// line, to a SyntaxError giving its line number instead
+func syntaxError(in []byte, err error) error {
+	if notFound, ok := err.(ini.ErrDelimiterNotFound); ok {
+		return &SyntaxError{Line: lineNumber(in, notFound.Line), Msg: "expected key = value"}
+	}
+	msg := err.Error()
+	for _, prefix := range []string{"unclosed section", "missing closing key quote"} {
+		if strings.HasPrefix(msg, prefix) {
+			return &SyntaxError{Line: lineNumber(in, strings.TrimPrefix(msg, prefix+": ")), Msg: prefix}
+		}
+	}
+	return &SyntaxError{Msg: "malformed input"}
+}
+
+// lineNumber returns the number of the first line of in matching line, or 0
+func lineNumber(in []byte, line string) int {
+	line = strings.TrimSpace(line)
+	for i, l := range strings.Split(string(in), "\n") {
+		if strings.TrimSpace(l) == line {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// sectionMap returns the map for the section at path, creating it and its
+// parents as needed
+func sectionMap(root map[string]interface{}, path []string) (map[string]interface{}, error) {
+	m := root
+	for i, name := range path {
+		switch existing := m[name].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			m[name] = child
+			m = child
+		case map[string]interface{}:
+			m = existing
+		default:
+			return nil, fmt.Errorf("section [%s] conflicts with a key of the same name", strings.Join(path[:i+1], "."))
+		}
+	}
+	return m, nil
+}
+
+// decodeValue converts value to a bool or number if TypedValues is set and it
+// is the canonical representation of one, so that e.g. "007" stays a string
+func decodeValue(value string, opts Options) interface{} {
+	if !opts.TypedValues {
+		return value
+	}
+	if b, err := strconv.ParseBool(value); err == nil && strconv.FormatBool(b) == value {
+		return b
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(i, 10) == value {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value {
+		return f
+	}
+	return value
+}
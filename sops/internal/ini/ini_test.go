@@ -2,6 +2,7 @@ package ini
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -25,3 +26,93 @@ example_key = example_value`)
 		t.Errorf("Unexpected output, expected %v, got %v", expectedOutput, data)
 	}
 }
+
+func TestUnmarshalWithOptions(t *testing.T) {
+	input := []byte(`port = 5432
+[db.primary]
+host = primary.example
+enabled = true
+[db.replica]
+host = replica-1.example
+host = replica-2.example
+weight = 0.5
+pin = 007`)
+
+	tc := []struct {
+		name     string
+		opts     Options
+		expected map[string]interface{}
+	}{
+		{
+			name: "defaults",
+			expected: map[string]interface{}{
+				"port":       "5432",
+				"db.primary": map[string]interface{}{"host": "primary.example", "enabled": "true"},
+				"db.replica": map[string]interface{}{"host": "replica-2.example", "weight": "0.5", "pin": "007"},
+			},
+		},
+		{
+			name: "all options",
+			opts: Options{NestedSections: true, TypedValues: true},
+			expected: map[string]interface{}{
+				"port": int64(5432),
+				"db": map[string]interface{}{
+					"primary": map[string]interface{}{"host": "primary.example", "enabled": true},
+					"replica": map[string]interface{}{
+						"host":   "replica-2.example",
+						"weight": 0.5,
+						"pin":    "007",
+					},
+				},
+			},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := UnmarshalWithOptions(input, &data, c.opts); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, data) {
+				t.Errorf("Unexpected output, expected %v, got %v", c.expected, data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_conflict(t *testing.T) {
+	input := []byte(`db = foo
+[db.primary]
+host = primary.example`)
+	var data map[string]interface{}
+	if err := UnmarshalWithOptions(input, &data, Options{NestedSections: true}); err == nil {
+		t.Error("Expected an error for a section conflicting with a key")
+	}
+}
+
+func TestUnmarshal_syntaxError(t *testing.T) {
+	tc := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{name: "missing delimiter", input: "[db]\nuser = foo\nsecretvalue\n", line: 3},
+		{name: "unclosed section", input: "key = value\n[secret\n", line: 2},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			err := Unmarshal([]byte(c.input), &data)
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Line != c.line {
+				t.Errorf("Expected error on line %d, got %d", c.line, syntaxErr.Line)
+			}
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("Error message contains input data: %s", err)
+			}
+		})
+	}
+}
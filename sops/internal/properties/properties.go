@@ -0,0 +1,125 @@
+package properties
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal parses Java .properties formatted data.
+//
+// Lines starting with # or ! are comments. Keys are separated from their
+// values by =, : or whitespace, and a line ending with an odd number of
+// backslashes continues on the next line. The escape sequences \t, \n, \r,
+// \f and \uXXXX are expanded in keys and values, and any other escaped
+// character is taken literally.
+func Unmarshal(in []byte, out *map[string]interface{}) error {
+	if *out == nil {
+		*out = make(map[string]interface{})
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(in), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continues(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if continues(line) {
+			line = line[:len(line)-1]
+		}
+
+		rawKey, rawValue := split(line)
+		key, err := unescape(rawKey)
+		if err != nil {
+			return &SyntaxError{Line: lineNumber, Msg: err.Error()}
+		}
+		value, err := unescape(rawValue)
+		if err != nil {
+			return &SyntaxError{Line: lineNumber, Msg: err.Error()}
+		}
+		(*out)[key] = value
+	}
+	return nil
+}
+
+// SyntaxError describes a malformed line. It deliberately carries no content
+// from the input, which may be a decrypted secret.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid properties input on line %d: %s", e.Line, e.Msg)
+}
+
+// continues reports whether line ends with an unescaped backslash
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// split separates a logical line into its raw key and value
+func split(line string) (string, string) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) != -1 {
+			end = i
+			break
+		}
+	}
+	key, rest := line[:end], strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+func unescape(s string) (string, error) {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\uXXXX escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uXXXX escape")
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,88 @@
+package properties
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	input := []byte(`# Comment!
+password=P@ssw0rd`)
+	expectedOutput := map[string]interface{}{
+		"password": "P@ssw0rd",
+	}
+	var data map[string]interface{}
+	err := Unmarshal(input, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expectedOutput, data) {
+		t.Errorf("Unexpected output, expected %v, got %v", expectedOutput, data)
+	}
+}
+
+func TestUnmarshal_syntax(t *testing.T) {
+	tc := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "separators",
+			input:    "a=1\nb : 2\nc 3\n  d\t=  4\ne",
+			expected: map[string]interface{}{"a": "1", "b": "2", "c": "3", "d": "4", "e": ""},
+		},
+		{
+			name:     "comments",
+			input:    "# a=1\n! b=2\nc=#3",
+			expected: map[string]interface{}{"c": "#3"},
+		},
+		{
+			name:     "dotted keys are kept as is",
+			input:    "db.password=secret",
+			expected: map[string]interface{}{"db.password": "secret"},
+		},
+		{
+			name:     "line continuations",
+			input:    "a=foo\\\n    bar\nb=baz\\\\\nc=qux",
+			expected: map[string]interface{}{"a": "foobar", "b": `baz\`, "c": "qux"},
+		},
+		{
+			name:     "escape sequences",
+			input:    `key\=with\:seps\ x=a\tb\nc\u00e9\\`,
+			expected: map[string]interface{}{"key=with:seps x": "a\tb\ncé\\"},
+		},
+		{
+			name:     "CRLF line endings",
+			input:    "a=1\r\nb=2\r\n",
+			expected: map[string]interface{}{"a": "1", "b": "2"},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := Unmarshal([]byte(c.input), &data); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, data) {
+				t.Errorf("Unexpected output, expected %q, got %q", c.expected, data)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	var data map[string]interface{}
+	err := Unmarshal([]byte("a=1\nsecret=\\uZZZZ"), &data)
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("Expected a syntax error, got %v", err)
+	}
+	if syntaxErr.Line != 2 {
+		t.Errorf("Expected error on line 2, got %d", syntaxErr.Line)
+	}
+	if strings.Contains(err.Error(), "secret") || strings.Contains(err.Error(), "ZZZZ") {
+		t.Errorf("Error message contains input data: %s", err)
+	}
+}
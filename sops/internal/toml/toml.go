@@ -0,0 +1,50 @@
+package toml
+
+import (
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func Unmarshal(in []byte, out *map[string]interface{}) error {
+	var data map[string]interface{}
+	if err := toml.Unmarshal(in, &data); err != nil {
+		return err
+	}
+
+	if *out == nil {
+		*out = make(map[string]interface{})
+	}
+	for k, v := range data {
+		(*out)[k] = normalize(v)
+	}
+	return nil
+}
+
+// normalize converts the types the TOML decoder produces into the generic
+// maps and lists produced by the other decoders. Date and time values are
+// kept as strings in RFC 3339 format.
+func normalize(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		for k, v := range typed {
+			typed[k] = normalize(v)
+		}
+		return typed
+	case []map[string]interface{}:
+		ret := make([]interface{}, len(typed))
+		for i, v := range typed {
+			ret[i] = normalize(v)
+		}
+		return ret
+	case []interface{}:
+		for i, v := range typed {
+			typed[i] = normalize(v)
+		}
+		return typed
+	case time.Time:
+		return typed.Format(time.RFC3339Nano)
+	default:
+		return typed
+	}
+}
@@ -0,0 +1,42 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	input := []byte(`# Comment!
+password = "P@ssw0rd"
+port = 5432
+rotated = 2024-01-02T03:04:05Z
+
+[db]
+user = "foo"
+
+[[servers]]
+host = "a.example"
+
+[[servers]]
+host = "b.example"`)
+	expectedOutput := map[string]interface{}{
+		"password": "P@ssw0rd",
+		"port":     int64(5432),
+		"rotated":  "2024-01-02T03:04:05Z",
+		"db": map[string]interface{}{
+			"user": "foo",
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a.example"},
+			map[string]interface{}{"host": "b.example"},
+		},
+	}
+	var data map[string]interface{}
+	err := Unmarshal(input, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expectedOutput, data) {
+		t.Errorf("Unexpected output, expected %v, got %v", expectedOutput, data)
+	}
+}
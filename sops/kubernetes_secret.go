@@ -0,0 +1,91 @@
+package sops
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+const decodeKubernetesSecret = "kubernetes_secret"
+
+const defaultKubernetesNamespace = "default"
+
+// decodeKubernetesSecrets replaces the data of every document holding a
+// Kubernetes Secret manifest with its secret values, and returns the values
+// of all Secrets keyed by namespace/name. As in Kubernetes, values in
+// stringData take precedence over base64-encoded values in data. Documents
+// of other kinds are left as is.
+func decodeKubernetesSecrets(docs []document) (map[string]map[string]string, error) {
+	secrets := make(map[string]map[string]string)
+	for i := range docs {
+		tree := docs[i].tree
+		if kind, _ := tree["kind"].(string); kind != "Secret" {
+			continue
+		}
+
+		metadata, _ := tree["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("Secret in document %d has no metadata.name", i)
+		}
+		namespace, _ := metadata["namespace"].(string)
+		if namespace == "" {
+			namespace = defaultKubernetesNamespace
+		}
+		id := namespace + "/" + name
+		if _, ok := secrets[id]; ok {
+			return nil, fmt.Errorf("Secret %s is defined more than once", id)
+		}
+
+		values := make(map[string]string)
+		data, err := secretField(tree, "data")
+		if err != nil {
+			return nil, fmt.Errorf("Secret %s: %w", id, err)
+		}
+		for k, v := range data {
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("Secret %s: value of data.%s is not valid base64", id, k)
+			}
+			values[k] = string(decoded)
+		}
+		stringData, err := secretField(tree, "stringData")
+		if err != nil {
+			return nil, fmt.Errorf("Secret %s: %w", id, err)
+		}
+		for k, v := range stringData {
+			values[k] = v
+		}
+
+		docs[i].Data = values
+		docs[i].tree = make(map[string]interface{}, len(values))
+		for k, v := range values {
+			docs[i].tree[k] = v
+		}
+		docs[i].decoded = true
+		docs[i].collisions = nil
+		secrets[id] = values
+	}
+	return secrets, nil
+}
+
+// secretField returns the string values of the data or stringData field of a
+// Secret manifest
+func secretField(tree map[string]interface{}, field string) (map[string]string, error) {
+	raw, ok := tree[field]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a map", field)
+	}
+	ret := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("value of %s.%s is not a string", field, k)
+		}
+		ret[k] = s
+	}
+	return ret, nil
+}
@@ -0,0 +1,63 @@
+package sops
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeKubernetesSecrets(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/kubernetes-secrets.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, _, err := readDocuments(context.Background(), content, "yaml", readOptions{flattener: newFlattener(defaultKeySeparator, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets, err := decodeKubernetesSecrets(docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]map[string]string{
+		"apps/db":     {"password": "P@ssw0rd", "username": "admin"},
+		"default/api": {"token": "s3cr3t"},
+	}
+	if !reflect.DeepEqual(expected, secrets) {
+		t.Errorf("Unexpected secrets, expected %v, got %v", expected, secrets)
+	}
+	if !reflect.DeepEqual(expected["apps/db"], docs[0].Data) {
+		t.Errorf("Unexpected data in document 0, expected %v, got %v", expected["apps/db"], docs[0].Data)
+	}
+	if docs[1].Data["data.log_level"] != "debug" {
+		t.Errorf("Expected the ConfigMap document to be left as is, got %v", docs[1].Data)
+	}
+}
+
+func TestDecodeKubernetesSecrets_errors(t *testing.T) {
+	secret := func(namespace, name string, data interface{}) document {
+		return document{tree: map[string]interface{}{
+			"kind":     "Secret",
+			"metadata": map[string]interface{}{"namespace": namespace, "name": name},
+			"data":     data,
+		}}
+	}
+	tc := []struct {
+		name string
+		docs []document
+	}{
+		{"missing name", []document{secret("apps", "", nil)}},
+		{"duplicate", []document{secret("apps", "db", nil), secret("apps", "db", nil)}},
+		{"invalid base64", []document{secret("apps", "db", map[string]interface{}{"password": "not base64!"})}},
+		{"data is not a map", []document{secret("apps", "db", "secret")}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := decodeKubernetesSecrets(c.docs); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
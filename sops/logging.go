@@ -0,0 +1,102 @@
+package sops
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/logging"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Log subsystems of the provider
+const (
+	logEncrypt    = "sops.encrypt"
+	logDecrypt    = "sops.decrypt"
+	logKeyService = "sops.keyservice"
+)
+
+// newLogContext returns ctx with the log subsystems of this provider, which
+// log path if it is set. The level of a subsystem can be set apart from the
+// provider, e.g. with TF_LOG_PROVIDER_SOPS_KEYSERVICE=trace.
+func newLogContext(ctx context.Context, path string) context.Context {
+	for _, subsystem := range []string{logEncrypt, logDecrypt, logKeyService} {
+		ctx = tflog.NewSubsystem(ctx, subsystem,
+			tflog.WithLevelFromEnv("TF_LOG_PROVIDER_SOPS", strings.TrimPrefix(subsystem, "sops.")),
+			tflog.WithRootFields(),
+		)
+		if path != "" {
+			ctx = tflog.SubsystemSetField(ctx, subsystem, "path", path)
+		}
+	}
+	return ctx
+}
+
+// The loggers of sops log without a context, so their output can't be told
+// apart between files read in parallel. It is dropped, and the outcome of
+// each master key is logged by keyServiceRecorder instead.
+func init() {
+	for _, logger := range logging.Loggers {
+		logger.SetOutput(io.Discard)
+	}
+}
+
+// logOperation logs the outcome of encrypting or decrypting data of format,
+// which started at start. subsystem is logEncrypt or logDecrypt, keyType the
+// types of the master keys involved, if known.
+func logOperation(ctx context.Context, subsystem, format, keyType string, start time.Time, err error) {
+	fields := map[string]interface{}{
+		"format":   format,
+		"duration": time.Since(start).String(),
+	}
+	if keyType != "" {
+		fields["key_type"] = keyType
+	}
+	done, failed := "Decrypted data", "Decryption failed"
+	if subsystem == logEncrypt {
+		done, failed = "Encrypted data", "Encryption failed"
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.SubsystemError(ctx, subsystem, failed, fields)
+		return
+	}
+	tflog.SubsystemDebug(ctx, subsystem, done, fields)
+}
+
+// keyGroupTypes returns the types of the master keys in groups, sorted and
+// separated by commas
+func keyGroupTypes(groups []mozillasops.KeyGroup) string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, group := range groups {
+		for _, key := range group {
+			if t := key.TypeToIdentifier(); !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	sort.Strings(types)
+	return strings.Join(types, ",")
+}
+
+// logKeyAttempt logs the result of encrypting or decrypting the data key with
+// a master key
+func logKeyAttempt(ctx context.Context, op string, a keyAttempt, duration time.Duration) {
+	fields := map[string]interface{}{
+		"operation": op,
+		"key_type":  a.keyType,
+		"key_id":    a.id,
+		"duration":  duration.String(),
+	}
+	if a.err != nil {
+		fields["error"] = a.err.Error()
+		tflog.SubsystemDebug(ctx, logKeyService, "Master key failed", fields)
+		return
+	}
+	tflog.SubsystemDebug(ctx, logKeyService, "Master key succeeded", fields)
+}
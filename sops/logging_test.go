@@ -0,0 +1,118 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestLogging(t *testing.T) {
+	const secret = "s3cr3t-value"
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(sopsage.SopsAgeKeyEnv, identity.String())
+	ageKey, err := sopsage.MasterKeyFromRecipient(identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	ctx := newLogContext(tflogtest.RootLogger(context.Background(), &out), "secrets.yaml")
+	store := storeForInputType(nil, "yaml")
+	content, err := Encrypt(ctx, EncryptOpts{
+		Cipher:      aes.NewCipher(),
+		InputStore:  store,
+		OutputStore: store,
+		InputPath:   "secrets.yaml",
+		KeyServices: LocalKeySvc(),
+		KeyGroups:   []mozillasops.KeyGroup{{ageKey}},
+	}, []byte("password: "+secret+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptData(ctx, content, "yaml", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(out.String(), secret) {
+		t.Errorf("Logs contain the secret value: %s", out.String())
+	}
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]map[string]interface{})
+	for _, e := range entries {
+		found[e["@module"].(string)+": "+e["@message"].(string)] = e
+	}
+	decrypted, ok := found["provider.sops.decrypt: Decrypted data"]
+	if !ok {
+		t.Fatalf("Expected a decrypt log entry, got %v", entries)
+	}
+	for k, v := range map[string]interface{}{"path": "secrets.yaml", "format": "yaml", "key_type": "age"} {
+		if decrypted[k] != v {
+			t.Errorf("Expected %s to be %v, got %v", k, v, decrypted[k])
+		}
+	}
+	if _, ok := decrypted["duration"]; !ok {
+		t.Error("Expected the duration to be logged")
+	}
+	if e := found["provider.sops.keyservice: Master key succeeded"]; e == nil || e["key_id"] != identity.Recipient().String() {
+		t.Errorf("Expected a log entry of the age key, got %v", entries)
+	}
+	// sops logs without a context, its output can't be attributed to a file
+	if e := found["provider.sops.keyservice: Encryption succeeded"]; e != nil {
+		t.Errorf("Expected the log entries of sops to be dropped, got %v", e)
+	}
+}
+
+func TestLogging_rotation(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "basic.yaml")
+	if err := os.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	ctx := newLogContext(tflogtest.RootLogger(context.Background(), &out), filename)
+	if _, err := rotateFile(ctx, filename, types.StringNull(), nil); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{
+		"provider.sops.decrypt: Decrypted data":          false,
+		"provider.sops.encrypt: Encrypted data":          false,
+		"provider.sops.keyservice: Master key succeeded": false,
+	}
+	for _, e := range entries {
+		key := e["@module"].(string) + ": " + e["@message"].(string)
+		if _, ok := expected[key]; ok && e["path"] == filename && e["key_type"] == "pgp" {
+			expected[key] = true
+		}
+	}
+	for key, found := range expected {
+		if !found {
+			t.Errorf("Expected a log entry %q for %s, got %v", key, filename, entries)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sops
+
+import "fmt"
+
+const (
+	listStrategyReplace = "replace"
+	listStrategyAppend  = "append"
+)
+
+// deepMerge merges src into dst, returning the result. Maps are merged
+// recursively, lists are combined according to listStrategy and any other
+// value in src replaces the value in dst.
+// e.g. {"a": {"b": 1, "c": 2}} + {"a": {"c": 3}} => {"a": {"b": 1, "c": 3}}
+func deepMerge(dst, src map[string]interface{}, listStrategy string) map[string]interface{} {
+	ret := make(map[string]interface{}, len(dst)+len(src))
+	for k, v := range dst {
+		ret[k] = v
+	}
+	for k, v := range src {
+		existing, ok := ret[k]
+		if !ok {
+			ret[k] = v
+			continue
+		}
+		ret[k] = mergeValues(existing, v, listStrategy)
+	}
+	return ret
+}
+
+func mergeValues(dst, src interface{}, listStrategy string) interface{} {
+	switch typedSrc := normalizeValue(src).(type) {
+	case map[string]interface{}:
+		if typedDst, ok := normalizeValue(dst).(map[string]interface{}); ok {
+			return deepMerge(typedDst, typedSrc, listStrategy)
+		}
+		return typedSrc
+	case []interface{}:
+		if typedDst, ok := dst.([]interface{}); ok && listStrategy == listStrategyAppend {
+			ret := make([]interface{}, 0, len(typedDst)+len(typedSrc))
+			return append(append(ret, typedDst...), typedSrc...)
+		}
+		return typedSrc
+	default:
+		return typedSrc
+	}
+}
+
+// originTree returns a structure shaped like v, with every leaf replaced by
+// origin. Merging origin trees the same way as the data they were derived
+// from results in a tree recording where each merged leaf came from.
+func originTree(v interface{}, origin string) interface{} {
+	switch typed := normalizeValue(v).(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			ret[k] = originTree(v, origin)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(typed))
+		for i, v := range typed {
+			ret[i] = originTree(v, origin)
+		}
+		return ret
+	default:
+		return origin
+	}
+}
+
+// normalizeValue converts maps with non-string keys, as produced by some YAML
+// decoders, into maps with string keys
+func normalizeValue(v interface{}) interface{} {
+	if typed, ok := v.(map[interface{}]interface{}); ok {
+		ret := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			ret[fmt.Sprint(k)] = v
+		}
+		return ret
+	}
+	return v
+}
@@ -0,0 +1,73 @@
+package sops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeepMerge(t *testing.T) {
+	tc := []struct {
+		name         string
+		dst          map[string]interface{}
+		src          map[string]interface{}
+		listStrategy string
+		expected     map[string]interface{}
+	}{
+		{
+			name:         "nested maps are merged",
+			dst:          map[string]interface{}{"db": map[string]interface{}{"user": "foo", "password": "bar"}},
+			src:          map[string]interface{}{"db": map[string]interface{}{"password": "baz"}},
+			listStrategy: listStrategyReplace,
+			expected:     map[string]interface{}{"db": map[string]interface{}{"user": "foo", "password": "baz"}},
+		},
+		{
+			name:         "lists are replaced",
+			dst:          map[string]interface{}{"a_list": []interface{}{1, 2}},
+			src:          map[string]interface{}{"a_list": []interface{}{3}},
+			listStrategy: listStrategyReplace,
+			expected:     map[string]interface{}{"a_list": []interface{}{3}},
+		},
+		{
+			name:         "lists are appended",
+			dst:          map[string]interface{}{"a_list": []interface{}{1, 2}},
+			src:          map[string]interface{}{"a_list": []interface{}{3}},
+			listStrategy: listStrategyAppend,
+			expected:     map[string]interface{}{"a_list": []interface{}{1, 2, 3}},
+		},
+		{
+			name:         "scalars replace maps",
+			dst:          map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			src:          map[string]interface{}{"a": nil},
+			listStrategy: listStrategyReplace,
+			expected:     map[string]interface{}{"a": nil},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			output := deepMerge(c.dst, c.src, c.listStrategy)
+			if !reflect.DeepEqual(c.expected, output) {
+				t.Errorf("Unexpected merge output, expected %v, got %v", c.expected, output)
+			}
+		})
+	}
+}
+
+func TestOriginTree(t *testing.T) {
+	common := map[string]interface{}{"db": map[string]interface{}{"user": "foo", "password": "bar"}, "a_list": []interface{}{1}}
+	prod := map[string]interface{}{"db": map[string]interface{}{"password": "baz"}, "a_list": []interface{}{2}}
+
+	origins := deepMerge(
+		originTree(common, "common.yaml").(map[string]interface{}),
+		originTree(prod, "prod.yaml").(map[string]interface{}),
+		listStrategyAppend,
+	)
+	expected := map[string]string{
+		"db.user":     "common.yaml",
+		"db.password": "prod.yaml",
+		"a_list.0":    "common.yaml",
+		"a_list.1":    "prod.yaml",
+	}
+	if output := flatten(origins); !reflect.DeepEqual(expected, output) {
+		t.Errorf("Unexpected origins, expected %v, got %v", expected, output)
+	}
+}
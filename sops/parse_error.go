@@ -0,0 +1,117 @@
+package sops
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/carlpett/terraform-provider-sops/sops/internal/dotenv"
+	"github.com/carlpett/terraform-provider-sops/sops/internal/hcl"
+	"github.com/carlpett/terraform-provider-sops/sops/internal/ini"
+	"github.com/carlpett/terraform-provider-sops/sops/internal/properties"
+)
+
+// parseError describes decrypted data that couldn't be parsed. Unlike the
+// errors of the parsers it is built from, which may quote the offending
+// input, it only carries the position of the problem and the key it was
+// found at.
+type parseError struct {
+	format string
+	// line and column are 0 if unknown
+	line, column int
+	// path is the key path of the problem, if known
+	path string
+	msg  string
+}
+
+func (e *parseError) Error() string {
+	msg := fmt.Sprintf("invalid %s data", e.format)
+	if e.line > 0 {
+		msg += fmt.Sprintf(" on line %d", e.line)
+		if e.column > 0 {
+			msg += fmt.Sprintf(", column %d", e.column)
+		}
+	}
+	if e.path != "" {
+		msg += fmt.Sprintf(" at %s", e.path)
+	}
+	return msg + ": " + e.msg
+}
+
+// yamlLine matches the line number in the errors of the YAML parser
+var yamlLine = regexp.MustCompile(`(?m)^(?:yaml: )?line (\d+):`)
+
+// redactParseError converts an error parsing the cleartext of format to one
+// that is safe to show, as it holds no content of the cleartext
+func redactParseError(format string, cleartext []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// The parsers of this provider already leave out the input
+	var (
+		dotenvErr     *dotenv.SyntaxError
+		hclErr        *hcl.SyntaxError
+		iniErr        *ini.SyntaxError
+		propertiesErr *properties.SyntaxError
+	)
+	if errors.As(err, &dotenvErr) || errors.As(err, &hclErr) || errors.As(err, &iniErr) || errors.As(err, &propertiesErr) {
+		return err
+	}
+
+	redacted := &parseError{format: format, msg: "malformed input"}
+	var (
+		jsonSyntaxErr *json.SyntaxError
+		jsonTypeErr   *json.UnmarshalTypeError
+		yamlTypeErr   *yaml.TypeError
+		tomlErr       toml.ParseError
+	)
+	switch {
+	case errors.As(err, &jsonSyntaxErr):
+		redacted.line, redacted.column = position(cleartext, jsonSyntaxErr.Offset)
+	case errors.As(err, &jsonTypeErr):
+		redacted.line, redacted.column = position(cleartext, jsonTypeErr.Offset)
+		redacted.path = jsonTypeErr.Field
+		redacted.msg = "unexpected type of value"
+	case errors.As(err, &yamlTypeErr):
+		if len(yamlTypeErr.Errors) > 0 {
+			redacted.line = yamlLineNumber(yamlTypeErr.Errors[0])
+		}
+		redacted.msg = "unexpected type of value"
+	case errors.As(err, &tomlErr):
+		redacted.line, redacted.column = position(cleartext, int64(tomlErr.Position.Start))
+		redacted.path = tomlErr.LastKey
+	case format == "yaml":
+		redacted.line = yamlLineNumber(err.Error())
+	}
+	return redacted
+}
+
+// yamlLineNumber returns the first line number in a message of the YAML
+// parser, or 0
+func yamlLineNumber(msg string) int {
+	m := yamlLine.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
+
+// position converts a byte offset in data to a line and column, both
+// starting at 1
+func position(data []byte, offset int64) (line, column int) {
+	if offset < 0 || offset > int64(len(data)) {
+		return 0, 0
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
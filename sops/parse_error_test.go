@@ -0,0 +1,59 @@
+package sops
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseData_redactsErrors(t *testing.T) {
+	const secret = "s3cr3t"
+	for _, tc := range []struct {
+		format    string
+		cleartext string
+		line      int
+	}{
+		{"json", "{\n  \"password\": s3cr3t\n}", 2},
+		{"json", "[\"s3cr3t\"]", 1},
+		{"yaml", "user: foo\npassword: s3cr3t: bar\n", 2},
+		{"yaml", "- s3cr3t\n", 1},
+		{"yaml", "password: \"s3cr3t\n", 0},
+		{"toml", "[db]\npassword = s3cr3t\n", 2},
+		{"dotenv", "USER=foo\ns3cr3t\n", 2},
+		{"ini", "[db]\ns3cr3t\n", 2},
+		{"properties", "password=\\us3cr3t\n", 1},
+		{"hcl", "password = s3cr3t(\n", 1},
+	} {
+		_, err := parseData([]byte(tc.cleartext), tc.format, readOptions{})
+		if err == nil {
+			t.Errorf("%s: expected an error parsing %q", tc.format, tc.cleartext)
+			continue
+		}
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("%s: error contains the input: %s", tc.format, err)
+		}
+		var parseErr *parseError
+		if errors.As(err, &parseErr) && tc.line > 0 && parseErr.line != tc.line {
+			t.Errorf("%s: expected the error on line %d, got %s", tc.format, tc.line, err)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	data := []byte("ab\ncde\nf")
+	for offset, expected := range map[int64][2]int{
+		0:  {1, 1},
+		2:  {1, 3},
+		3:  {2, 1},
+		5:  {2, 3},
+		7:  {3, 1},
+		8:  {3, 2},
+		9:  {0, 0},
+		-1: {0, 0},
+	} {
+		line, column := position(data, offset)
+		if line != expected[0] || column != expected[1] {
+			t.Errorf("offset %d: expected %v, got %d:%d", offset, expected, line, column)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package sops
+
+import (
+	"encoding/json"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/carlpett/terraform-provider-sops/sops/internal/dotenv"
+	"github.com/carlpett/terraform-provider-sops/sops/internal/hcl"
+	"github.com/carlpett/terraform-provider-sops/sops/internal/ini"
+	"github.com/carlpett/terraform-provider-sops/sops/internal/properties"
+	"github.com/carlpett/terraform-provider-sops/sops/internal/toml"
+)
+
+// parser describes how files of an input type are decrypted and decoded
+type parser struct {
+	// store is the sops format the file is encrypted with. Formats sops has
+	// no store for are encrypted in binary mode, and parsed after decryption.
+	store string
+	// extensions are the file extensions detected as this input type
+	extensions []string
+	// unmarshal decodes the cleartext. It is nil for input types without
+	// structure.
+	unmarshal func(cleartext []byte, out *map[string]interface{}, opts readOptions) error
+}
+
+// parsers holds the supported input types
+var parsers = map[string]parser{
+	"json": {
+		store:      "json",
+		extensions: []string{".json"},
+		unmarshal: func(cleartext []byte, out *map[string]interface{}, _ readOptions) error {
+			return json.Unmarshal(cleartext, out)
+		},
+	},
+	"yaml": {
+		store:      "yaml",
+		extensions: []string{".yaml", ".yml"},
+		unmarshal: func(cleartext []byte, out *map[string]interface{}, _ readOptions) error {
+			return yaml.Unmarshal(cleartext, out)
+		},
+	},
+	"dotenv": {
+		store:      "dotenv",
+		extensions: []string{".env"},
+		unmarshal: func(cleartext []byte, out *map[string]interface{}, _ readOptions) error {
+			return dotenv.Unmarshal(cleartext, out)
+		},
+	},
+	"ini": {
+		store:      "ini",
+		extensions: []string{".ini"},
+		unmarshal: func(cleartext []byte, out *map[string]interface{}, opts readOptions) error {
+			return ini.UnmarshalWithOptions(cleartext, out, opts.ini)
+		},
+	},
+	"toml": {
+		store:      "binary",
+		extensions: []string{".toml"},
+		unmarshal: func(cleartext []byte, out *map[string]interface{}, _ readOptions) error {
+			return toml.Unmarshal(cleartext, out)
+		},
+	},
+	"properties": {
+		store:      "binary",
+		extensions: []string{".properties"},
+		unmarshal: func(cleartext []byte, out *map[string]interface{}, _ readOptions) error {
+			return properties.Unmarshal(cleartext, out)
+		},
+	},
+	"hcl": {
+		store:      "binary",
+		extensions: []string{".tfvars", ".hcl"},
+		unmarshal: func(cleartext []byte, out *map[string]interface{}, _ readOptions) error {
+			return hcl.Unmarshal(cleartext, out)
+		},
+	},
+	"raw": {
+		store: "binary",
+	},
+}
+
+// inputTypes returns the names of the supported input types, sorted
+func inputTypes() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
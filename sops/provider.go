@@ -25,6 +25,7 @@ func (p *SopsProvider) Metadata(_ context.Context, _ provider.MetadataRequest, r
 func (p *SopsProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Blocks: map[string]schema.Block{
+			"stores": storesBlock(),
 			"kms": schema.SingleNestedBlock{
 				Attributes: map[string]schema.Attribute{
 					"arn": schema.StringAttribute{
@@ -52,8 +53,9 @@ func (p *SopsProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp
 func (p *SopsProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	// TODO: Hacky.
 	var encryptConfig struct {
-		Kms types.Object `tfsdk:"kms"`
-		Pgp types.Object `tfsdk:"pgp"`
+		Kms    types.Object       `tfsdk:"kms"`
+		Pgp    types.Object       `tfsdk:"pgp"`
+		Stores *storesConfigModel `tfsdk:"stores"`
 	}
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &encryptConfig)...)
@@ -79,18 +81,29 @@ func (p *SopsProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 		conf.EncryptionProvider = "pgp"
 	}
 
-	resp.ResourceData = conf
+	data := providerData{
+		encryptConfig: conf,
+		stores:        encryptConfig.Stores.storesConfig(),
+	}
+	resp.ResourceData = data
+	resp.DataSourceData = data
 }
 
 func (p *SopsProvider) DataSources(_ context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		newFileDataSource,
 		newExternalDataSource,
+		newPolicyCheckDataSource,
+		newFilesDataSource,
+		newMergedDataSource,
 	}
 }
 
 func (p *SopsProvider) Resources(_ context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		newFileResource,
+		newRotationResource,
+		newRecipientsResource,
+		newFileEntryResource,
 	}
 }
@@ -1,40 +1,233 @@
 package sops
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
-	"github.com/getsops/sops/v3"
-	"github.com/getsops/sops/v3/decrypt"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/codes"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"gopkg.in/yaml.v3"
-
-	"github.com/carlpett/terraform-provider-sops/sops/internal/dotenv"
-	"github.com/carlpett/terraform-provider-sops/sops/internal/ini"
 )
 
-func readData(content []byte, format string) (map[string]string, string, error) {
-	cleartext, err := decrypt.Data(content, format)
-	if userErr, ok := err.(sops.UserError); ok {
-		err = userErr
+func readData(ctx context.Context, content []byte, format string, stores *config.StoresConfig) (map[string]string, string, error) {
+	cleartext, err := decryptData(ctx, content, format, stores)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := parseData(cleartext, format, readOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return flatten(data), string(cleartext), nil
+}
+
+// decryptData decrypts sops-encrypted content, returning the cleartext as
+// emitted by the store of format
+func decryptData(ctx context.Context, content []byte, format string, stores *config.StoresConfig) ([]byte, error) {
+	start := time.Now()
+	recorder := newKeyServiceRecorder(ctx, "decrypt")
+	cleartext, err := decryptWithStore(content, storeForInputType(stores, format), recorder)
+	logOperation(ctx, logDecrypt, format, recorder.keyType(), start, err)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting sops file: %w", err)
+	}
+	return cleartext, nil
+}
+
+// decryptWithStore is decrypt.DataWithFormat, taking the store to use instead
+// of creating one with the default configuration. The data key is decrypted
+// with key services recorded by recorder. Errors carry the exit code of the
+// sops binary, and the attempts of each master key if the data key can't be
+// decrypted.
+func decryptWithStore(content []byte, store common.Store, recorder *keyServiceRecorder) ([]byte, error) {
+	tree, err := store.LoadEncryptedFile(content)
+	if err != nil {
+		return nil, &sopsError{code: codes.CouldNotReadInputFile, err: err}
+	}
+	key, err := tree.Metadata.GetDataKeyWithKeyServices(recorder.wrap(LocalKeySvc()), nil)
+	if err != nil {
+		return nil, &sopsError{code: codes.CouldNotRetrieveKey, err: recorder.failure(err)}
+	}
+
+	cipher := aes.NewCipher()
+	mac, err := tree.Decrypt(key, cipher)
+	if err != nil {
+		return nil, &sopsError{code: codes.ErrorDecryptingTree, err: err}
+	}
+
+	// Compare the hash of the cleartext tree with the one stored in the
+	// file to verify its integrity
+	originalMac, err := cipher.Decrypt(
+		tree.Metadata.MessageAuthenticationCode,
+		key,
+		tree.Metadata.LastModified.Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, &sopsError{code: codes.ErrorDecryptingMac, err: fmt.Errorf("Failed to decrypt original mac: %w", err)}
 	}
+	if originalMac != mac {
+		// The MACs are hashes of the cleartext, and are left out
+		return nil, &sopsError{code: codes.MacMismatch, err: fmt.Errorf("Failed to verify data integrity: the MAC doesn't match the content of the file")}
+	}
+
+	cleartext, err := store.EmitPlainFile(tree.Branches)
 	if err != nil {
-		return nil, "", fmt.Errorf("Error decrypting sops file: %w", err)
+		return nil, &sopsError{code: codes.ErrorDumpingTree, err: err}
 	}
+	return cleartext, nil
+}
 
+// parseData unmarshals decrypted content into a nested structure. Raw content
+// has no structure, and results in an empty map.
+func parseData(cleartext []byte, format string, opts readOptions) (map[string]interface{}, error) {
 	var data map[string]interface{}
-	switch format {
-	case "json":
-		err = json.Unmarshal(cleartext, &data)
-	case "yaml":
-		err = yaml.Unmarshal(cleartext, &data)
-	case "dotenv":
-		err = dotenv.Unmarshal(cleartext, &data)
-	case "ini":
-		err = ini.Unmarshal(cleartext, &data)
+	unmarshal := parsers[format].unmarshal
+	if unmarshal == nil {
+		return data, nil
+	}
+	if err := unmarshal(cleartext, &data, opts); err != nil {
+		return nil, fmt.Errorf("Error parsing decrypted data: %w", redactParseError(format, cleartext, err))
 	}
+	return data, nil
+}
+
+// document is a single document of a decrypted file. Only YAML files can hold
+// more than one document.
+type document struct {
+	Data map[string]string `tfsdk:"data"`
+	Raw  string            `tfsdk:"raw"`
+
+	// tree is the unflattened data
+	tree map[string]interface{}
+	// decoded is set when Data was replaced by decoding. tree then holds the
+	// decoded values, keyed like Data.
+	decoded bool
+	// collisions are the keys of Data that several paths were flattened to
+	collisions []string
+}
+
+// newDocument returns a document of tree, flattened like f. Each document
+// is flattened separately, so that only its own collisions are recorded.
+func newDocument(tree map[string]interface{}, raw string, f *flattener) document {
+	f = newFlattener(f.separator, f.escapeKeys)
+	return document{Data: f.flatten(tree), Raw: raw, tree: tree, collisions: f.collisions}
+}
+
+var documentAttrTypes = map[string]attr.Type{
+	"data": types.MapType{ElemType: types.StringType},
+	"raw":  types.StringType,
+}
+
+// readDocuments decrypts content and splits it into its documents
+func readDocuments(ctx context.Context, content []byte, format string, opts readOptions) ([]document, string, error) {
+	cleartext, err := decryptData(ctx, content, format, opts.stores)
 	if err != nil {
-		return nil, "", fmt.Errorf("Error parsing decrypted data: %w", err)
+		return nil, "", err
 	}
 
-	return flatten(data), string(cleartext), nil
+	if format != "yaml" {
+		data, err := parseData(cleartext, format, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return []document{newDocument(data, string(cleartext), opts.flattener)}, string(cleartext), nil
+	}
+
+	var docs []document
+	segments := splitYAMLDocuments(cleartext)
+	decoder := yaml.NewDecoder(bytes.NewReader(cleartext))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, "", fmt.Errorf("Error parsing decrypted data: %w", redactParseError(format, cleartext, err))
+		}
+
+		var data map[string]interface{}
+		if err := node.Decode(&data); err != nil {
+			return nil, "", fmt.Errorf("Error parsing decrypted data in document %d: %w", len(docs), redactParseError(format, cleartext, err))
+		}
+		var raw string
+		if len(node.Content) > 0 {
+			raw = segments.at(node.Content[0].Line)
+		}
+		docs = append(docs, newDocument(data, raw, opts.flattener))
+	}
+	return docs, string(cleartext), nil
+}
+
+// yamlSegment is the text of a YAML document, from the line of its document
+// marker, if any, to the line before the next one
+type yamlSegment struct {
+	firstLine, lastLine int
+	text                string
+}
+
+type yamlSegments []yamlSegment
+
+// splitYAMLDocuments splits YAML text at its document markers, "---" and
+// "...". Markers are only recognized at the start of a line, where they can't
+// occur inside a document. The marker lines are left out of the text, except
+// for content following a "---" on the same line.
+func splitYAMLDocuments(text []byte) yamlSegments {
+	segments := yamlSegments{{firstLine: 1}}
+	var current strings.Builder
+	lines := strings.SplitAfter(string(text), "\n")
+	for i, line := range lines {
+		marker := ""
+		for _, m := range []string{"---", "..."} {
+			if rest := strings.TrimPrefix(line, m); rest != line && (rest == "" || strings.ContainsRune(" \t\r\n", rune(rest[0]))) {
+				marker = m
+			}
+		}
+		if marker == "" {
+			current.WriteString(line)
+			continue
+		}
+
+		segments[len(segments)-1].lastLine = i
+		segments[len(segments)-1].text = current.String()
+		current.Reset()
+		segments = append(segments, yamlSegment{firstLine: i + 1})
+		if rest := strings.TrimLeft(line[len(marker):], " \t"); marker == "---" && strings.TrimSpace(rest) != "" {
+			current.WriteString(rest)
+		}
+	}
+	segments[len(segments)-1].lastLine = len(lines)
+	segments[len(segments)-1].text = current.String()
+	return segments
+}
+
+// at returns the text of the segment holding line
+func (s yamlSegments) at(line int) string {
+	for _, segment := range s {
+		if segment.firstLine <= line && line <= segment.lastLine {
+			return segment.text
+		}
+	}
+	return ""
+}
+
+// selectDocument returns the document at index, or the first document if no
+// index is given
+func selectDocument(docs []document, index types.Int64) (document, error) {
+	i := int(index.ValueInt64())
+	if index.IsNull() && len(docs) == 0 {
+		return document{Data: map[string]string{}}, nil
+	}
+	if i < 0 || i >= len(docs) {
+		return document{}, fmt.Errorf("document_index %d is out of range, the file has %d documents", i, len(docs))
+	}
+	return docs[i], nil
 }
@@ -0,0 +1,144 @@
+package sops
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"gopkg.in/yaml.v3"
+)
+
+func TestReadDocuments(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/multi-document.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, raw, err := readDocuments(context.Background(), content, "yaml", readOptions{flattener: newFlattener(defaultKeySeparator, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]string{
+		{"name": "first", "db.password": "foo"},
+		{"name": "second", "db.password": "bar"},
+	}
+	if len(docs) != len(expected) {
+		t.Fatalf("Expected %d documents, got %d", len(expected), len(docs))
+	}
+	for i, doc := range docs {
+		if !reflect.DeepEqual(expected[i], doc.Data) {
+			t.Errorf("Unexpected data in document %d, expected %v, got %v", i, expected[i], doc.Data)
+		}
+	}
+
+	for i, doc := range docs {
+		var data map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc.Raw), &data); err != nil {
+			t.Fatal(err)
+		}
+		if data["name"] != expected[i]["name"] || !strings.Contains(raw, doc.Raw) {
+			t.Errorf("Expected the raw text of document %d to be part of the cleartext, got %q", i, doc.Raw)
+		}
+	}
+
+	if _, err := selectDocument(docs, types.Int64Value(2)); err == nil {
+		t.Error("Expected an error for an out of range document index")
+	}
+}
+
+func TestReadDocuments_collisions(t *testing.T) {
+	model := fileResourceAPIModel{
+		Filename:  "secrets.yaml",
+		InputType: "yaml",
+		EncryptConfig: encryptConfigModel{
+			EncryptionProvider: "pgp",
+			Pgp:                PgpConf{Fingerprint: testPgpFingerprint},
+		},
+	}
+	content, err := sopsEncrypt(context.Background(), model, []byte("name: first\n---\na.b: 1\na:\n  b: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, _, err := readDocuments(context.Background(), content, "yaml", readOptions{flattener: newFlattener(defaultKeySeparator, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+	if docs[0].collisions != nil {
+		t.Errorf("Expected no collisions in document 0, got %v", docs[0].collisions)
+	}
+	if expected := []string{"a.b"}; !reflect.DeepEqual(expected, docs[1].collisions) {
+		t.Errorf("Expected collisions %v in document 1, got %v", expected, docs[1].collisions)
+	}
+}
+
+func TestSplitYAMLDocuments(t *testing.T) {
+	text := "# first\na: 1\n---\n# second\nb:\n  - 2\n...\n--- {c: 3}\n---\n"
+	expected := yamlSegments{
+		{firstLine: 1, lastLine: 2, text: "# first\na: 1\n"},
+		{firstLine: 3, lastLine: 6, text: "# second\nb:\n  - 2\n"},
+		{firstLine: 7, lastLine: 7, text: ""},
+		{firstLine: 8, lastLine: 8, text: "{c: 3}\n"},
+		{firstLine: 9, lastLine: 10, text: ""},
+	}
+	segments := splitYAMLDocuments([]byte(text))
+	if !reflect.DeepEqual(expected, segments) {
+		t.Errorf("Expected %+v, got %+v", expected, segments)
+	}
+	for line, expected := range map[int]string{2: "# first\na: 1\n", 5: "# second\nb:\n  - 2\n", 8: "{c: 3}\n"} {
+		if actual := segments.at(line); actual != expected {
+			t.Errorf("line %d: expected %q, got %q", line, expected, actual)
+		}
+	}
+}
+
+func TestReadData_binaryFormats(t *testing.T) {
+	tc := []struct {
+		file     string
+		expected map[string]string
+	}{
+		{"test-fixtures/secrets.toml", map[string]string{"password": "P@ssw0rd", "port": "5432", "db.user": "foo"}},
+		{"test-fixtures/secrets.properties", map[string]string{"db.password": "P@ssw0rd", "db.user": "foo"}},
+		{"test-fixtures/secrets.enc.tfvars", map[string]string{"db_password": "P@ssw0rd", "replicas": "3", "admins.0": "alice", "admins.1": "bob", "smtp.user": "mailer"}},
+	}
+	for _, c := range tc {
+		t.Run(c.file, func(t *testing.T) {
+			content, err := os.ReadFile(c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			format, ok := inputTypeForPath(c.file)
+			if !ok {
+				t.Fatalf("Unknown extension of %s", c.file)
+			}
+			data, _, err := readData(context.Background(), content, format, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, data) {
+				t.Errorf("Unexpected data, expected %v, got %v", c.expected, data)
+			}
+		})
+	}
+}
+
+func TestReadTree_typedValues(t *testing.T) {
+	data, err := readTree(context.Background(), "test-fixtures/secrets.enc.tfvars", types.StringNull(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"db_password": "P@ssw0rd",
+		"replicas":    int64(3),
+		"admins":      []interface{}{"alice", "bob"},
+		"smtp":        map[string]interface{}{"user": "mailer"},
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Errorf("Unexpected data, expected %v, got %v", expected, data)
+	}
+}
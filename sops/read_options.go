@@ -0,0 +1,72 @@
+package sops
+
+import (
+	"context"
+
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"github.com/carlpett/terraform-provider-sops/sops/internal/ini"
+)
+
+// readOptions configure how decrypted data is parsed and flattened
+type readOptions struct {
+	flattener *flattener
+	ini       ini.Options
+	// stores configures the stores emitting the decrypted content
+	stores *config.StoresConfig
+}
+
+// readOptionsModel holds the data source attributes configuring readOptions
+type readOptionsModel struct {
+	KeySeparator types.String
+	EscapeKeys   types.Bool
+	Ini          types.Object
+	Stores       *config.StoresConfig
+}
+
+type iniOptionsModel struct {
+	NestedSections types.Bool `tfsdk:"nested_sections"`
+	TypedValues    types.Bool `tfsdk:"typed_values"`
+}
+
+func (m readOptionsModel) readOptions(ctx context.Context) (readOptions, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	opts := readOptions{
+		flattener: newFlattener(defaultKeySeparator, m.EscapeKeys.ValueBool()),
+		stores:    m.Stores,
+	}
+	if !m.KeySeparator.IsNull() {
+		opts.flattener.separator = m.KeySeparator.ValueString()
+	}
+
+	if !m.Ini.IsNull() {
+		var iniOpts iniOptionsModel
+		diags.Append(m.Ini.As(ctx, &iniOpts, basetypes.ObjectAsOptions{})...)
+		opts.ini = ini.Options{
+			NestedSections: iniOpts.NestedSections.ValueBool(),
+			TypedValues:    iniOpts.TypedValues.ValueBool(),
+		}
+	}
+	return opts, diags
+}
+
+func iniOptionsAttribute() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Description: "Options for decoding INI data",
+		Optional:    true,
+		Attributes: map[string]schema.Attribute{
+			"nested_sections": schema.BoolAttribute{
+				Description: "Decode dotted section names such as [db.primary] as nested maps",
+				Optional:    true,
+			},
+			"typed_values": schema.BoolAttribute{
+				Description: "Decode boolean and numeric values as such, instead of as strings",
+				Optional:    true,
+			},
+		},
+	}
+}
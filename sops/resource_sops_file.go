@@ -5,43 +5,60 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/config"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/dynamicplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
+var _ resource.ResourceWithUpgradeState = &fileResource{}
+
 func newFileResource() resource.Resource {
 	return &fileResource{}
 }
 
 type fileResource struct {
 	rootEncryptConfig encryptConfigModel
+	stores            *config.StoresConfig
 }
 
 type fileResourceModel struct {
-	ID                  types.String `tfsdk:"id"`
-	SensitiveContent    types.String `tfsdk:"sensitive_content"`
-	ContentBase64       types.String `tfsdk:"content_base64"`
-	Source              types.String `tfsdk:"source"`
-	Content             types.String `tfsdk:"content"`
-	FilePermission      types.String `tfsdk:"file_permission"`
-	DirectoryPermission types.String `tfsdk:"directory_permission"`
-	Filename            types.String `tfsdk:"filename"`
-	EncryptedRegex      types.String `tfsdk:"encrypted_regex"`
+	ID                  types.String  `tfsdk:"id"`
+	SensitiveContent    types.String  `tfsdk:"sensitive_content"`
+	ContentBase64       types.String  `tfsdk:"content_base64"`
+	Source              types.String  `tfsdk:"source"`
+	Content             types.String  `tfsdk:"content"`
+	FilePermission      types.String  `tfsdk:"file_permission"`
+	DirectoryPermission types.String  `tfsdk:"directory_permission"`
+	Owner               types.String  `tfsdk:"owner"`
+	Group               types.String  `tfsdk:"group"`
+	DestroyBehavior     types.String  `tfsdk:"destroy_behavior"`
+	Filename            types.String  `tfsdk:"filename"`
+	EncryptedRegex      types.String  `tfsdk:"encrypted_regex"`
+	InputType           types.String  `tfsdk:"input_type"`
+	OutputType          types.String  `tfsdk:"output_type"`
+	SecretValues        types.Dynamic `tfsdk:"secret_values"`
+	PublicValues        types.Dynamic `tfsdk:"public_values"`
 
 	Kms types.Object `tfsdk:"kms"`
 	Pgp types.Object `tfsdk:"pgp"`
@@ -55,22 +72,84 @@ type fileResourceAPIModel struct {
 	Content             string
 	FilePermission      string
 	DirectoryPermission string
+	Owner               string
+	Group               string
 	EncryptedRegex      string
+	UnencryptedRegex    string
+	InputType           string
+	OutputType          string
 	EncryptConfig       encryptConfigModel
+	Stores              *config.StoresConfig
 }
 
 func (f *fileResource) Configure(_ context.Context, req resource.ConfigureRequest, _ *resource.ConfigureResponse) {
-	f.rootEncryptConfig, _ = req.ProviderData.(encryptConfigModel)
+	pd, _ := req.ProviderData.(providerData)
+	f.rootEncryptConfig = pd.encryptConfig
+	f.stores = configuredStores(req.ProviderData)
 }
 
 func (fileResource) Delete(ctx context.Context, req resource.DeleteRequest, res *resource.DeleteResponse) {
-	var filename string
-	if ds := req.State.GetAttribute(ctx, tfpath.Root("filename"), &filename); ds.HasError() {
+	var state fileResourceModel
+	if ds := req.State.Get(ctx, &state); ds.HasError() {
 		res.Diagnostics.Append(ds...)
 		return
 	}
+	filename := state.Filename.ValueString()
+
+	// States from before destroy_behavior was added have it unset
+	behavior := state.DestroyBehavior.ValueString()
+	if behavior == destroyBehaviorRetain {
+		return
+	}
+
+	// Like Read, treat a missing file, or a missing directory, as deleted.
+	// The directory can't be locked if it's gone.
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return
+	}
+
+	unlock, err := lockFile(filename)
+	if err != nil {
+		res.Diagnostics.AddError("failed to lock file", err.Error())
+		return
+	}
+	defer unlock()
+
+	if behavior == destroyBehaviorArchive {
+		archived, err := archiveFile(filename, time.Now())
+		if err != nil {
+			res.Diagnostics.AddError(fmt.Sprintf("failed to archive %s", filename), err.Error())
+			return
+		}
+		if archived != "" {
+			res.Diagnostics.AddWarning("file archived", fmt.Sprintf("%s was moved to %s", filename, archived))
+		}
+		return
+	}
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		res.Diagnostics.AddError(fmt.Sprintf("failed to delete %s", filename), err.Error())
+	}
+}
+
+const (
+	destroyBehaviorDelete  = "delete"
+	destroyBehaviorRetain  = "retain"
+	destroyBehaviorArchive = "archive"
+)
 
-	os.Remove(filename)
+// archiveFile moves a file to a backup next to it, named after the time of
+// the archival, and returns the name of the backup. It returns an empty name
+// if the file doesn't exist.
+func archiveFile(filename string, now time.Time) (string, error) {
+	archived := fmt.Sprintf("%s.%s.bak", filename, now.UTC().Format("20060102T150405Z"))
+	if err := os.Rename(filename, archived); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return archived, nil
 }
 
 func (fileResource) Metadata(_ context.Context, _ resource.MetadataRequest, res *resource.MetadataResponse) {
@@ -121,6 +200,7 @@ func (fileResource) Read(ctx context.Context, req resource.ReadRequest, res *res
 
 func (fileResource) Schema(_ context.Context, _ resource.SchemaRequest, res *resource.SchemaResponse) {
 	res.Schema = schema.Schema{
+		Version: 1,
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Computed: true,
@@ -156,16 +236,18 @@ func (fileResource) Schema(_ context.Context, _ resource.SchemaRequest, res *res
 				},
 			},
 			"file_permission": schema.StringAttribute{
-				Description: "Permissions to set for the output file",
+				Description: "Permissions to set for the output file. Defaults to 0600",
 				Optional:    true,
 				Computed:    true,
-				Default:     stringdefault.StaticString("0777"),
 				Validators: []validator.String{
 					stringvalidator.LengthAtMost(4),
 					stringvalidator.LengthAtLeast(3),
 					&octalValidator{},
 				},
 				PlanModifiers: []planmodifier.String{
+					// Resources created with the earlier default of 0777
+					// keep it, rather than being replaced
+					&legacyDefault{value: "0600", legacy: "0777"},
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
@@ -183,9 +265,67 @@ func (fileResource) Schema(_ context.Context, _ resource.SchemaRequest, res *res
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"owner": schema.StringAttribute{
+				Description: "User owning the output file, as a name or numeric ID",
+				Optional:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"group": schema.StringAttribute{
+				Description: "Group owning the output file, as a name or numeric ID",
+				Optional:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"destroy_behavior": schema.StringAttribute{
+				Description: "What happens to the file when the resource is destroyed: delete, retain, or archive to move it to a timestamped backup. Defaults to delete",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(destroyBehaviorDelete),
+				Validators: []validator.String{
+					stringvalidator.OneOf(destroyBehaviorDelete, destroyBehaviorRetain, destroyBehaviorArchive),
+				},
+			},
 			"encrypted_regex": schema.StringAttribute{
-				Description: "A regex pattern denoting the contents in the file to be encrypted",
+				Description: "A regex pattern denoting the keys whose values are encrypted. All values are encrypted by default",
+				Optional:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"input_type": schema.StringAttribute{
+				Description: "Type of the content: json, yaml, dotenv, ini, or raw to encrypt it as is. Detected from the filename by default",
 				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(inputTypes()...),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"secret_values": schema.DynamicAttribute{
+				Description: "Values to encrypt, as a map. The file is built from secret_values and public_values instead of content",
+				Optional:    true,
+				Sensitive:   true,
+				PlanModifiers: []planmodifier.Dynamic{
+					dynamicplanmodifier.RequiresReplace(),
+				},
+			},
+			"public_values": schema.DynamicAttribute{
+				Description: "Values to store in cleartext next to secret_values, as a map",
+				Optional:    true,
+				PlanModifiers: []planmodifier.Dynamic{
+					dynamicplanmodifier.RequiresReplace(),
+				},
+			},
+			"output_type": schema.StringAttribute{
+				Description: "Type of the encrypted file: json, yaml, dotenv, ini, or raw for sops' binary format. The content is converted if it differs from input_type. Detected from the filename by default",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(inputTypes()...),
+				},
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplace(),
 				},
@@ -221,13 +361,61 @@ func (fileResource) Schema(_ context.Context, _ resource.SchemaRequest, res *res
 	}
 }
 
-func (fileResource) Update(context.Context, resource.UpdateRequest, *resource.UpdateResponse) {
-	panic("unimplemented")
+// fileAttributesSinceV0 are the string attributes added to sops_file since
+// version 0 of its schema, which are null in upgraded states
+var fileAttributesSinceV0 = map[string]bool{
+	"owner":            true,
+	"group":            true,
+	"destroy_behavior": true,
+}
+
+// UpgradeState upgrades states from before owner and group were added. The
+// file_permission of these resources is kept, see legacyDefault.
+func (f fileResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	var current resource.SchemaResponse
+	f.Schema(ctx, resource.SchemaRequest{}, &current)
+
+	priorSchema := current.Schema
+	priorSchema.Version = 0
+	priorSchema.Attributes = make(map[string]schema.Attribute, len(current.Schema.Attributes))
+	for name, attr := range current.Schema.Attributes {
+		if !fileAttributesSinceV0[name] {
+			priorSchema.Attributes[name] = attr
+		}
+	}
+
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &priorSchema,
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var values map[string]tftypes.Value
+				if err := req.State.Raw.As(&values); err != nil {
+					resp.Diagnostics.AddError("failed to upgrade state", err.Error())
+					return
+				}
+				for name := range fileAttributesSinceV0 {
+					values[name] = tftypes.NewValue(tftypes.String, nil)
+				}
+				resp.State.Raw = tftypes.NewValue(resp.State.Schema.Type().TerraformType(ctx), values)
+			},
+		},
+	}
+}
+
+// Update only records changes to arguments that don't affect the file, all
+// others replace it
+func (fileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan fileResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
 
 func (f fileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var tfm fileResourceModel
-	if ds := req.Config.Get(ctx, &tfm); ds.HasError() {
+	if ds := req.Plan.Get(ctx, &tfm); ds.HasError() {
 		resp.Diagnostics.Append(ds...)
 		return
 	}
@@ -236,12 +424,17 @@ func (f fileResource) Create(ctx context.Context, req resource.CreateRequest, re
 		Filename:            tfm.Filename.ValueString(),
 		FilePermission:      tfm.FilePermission.ValueString(),
 		DirectoryPermission: tfm.DirectoryPermission.ValueString(),
+		Owner:               tfm.Owner.ValueString(),
+		Group:               tfm.Group.ValueString(),
 		SensitiveContent:    tfm.SensitiveContent.ValueString(),
 		ContentBase64:       tfm.ContentBase64.ValueString(),
 		Content:             tfm.Content.ValueString(),
 		Source:              tfm.Source.ValueString(),
 		EncryptedRegex:      tfm.EncryptedRegex.ValueString(),
+		InputType:           tfm.InputType.ValueString(),
+		OutputType:          tfm.OutputType.ValueString(),
 		EncryptConfig:       f.rootEncryptConfig,
+		Stores:              f.stores,
 	}
 
 	// TODO Only allow one of kms or pgp to be defined
@@ -273,15 +466,24 @@ func (f fileResource) Create(ctx context.Context, req resource.CreateRequest, re
 		return
 	}
 
-	content, err := resourceLocalFileContent(model)
-	if err != nil {
-		resp.Diagnostics.AddError("base64 decode failure", err.Error())
-		return
+	var content []byte
+	if !tfm.SecretValues.IsNull() || !tfm.PublicValues.IsNull() {
+		content = resourceValuesContent(tfm, &model, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	} else {
+		var err error
+		content, err = resourceLocalFileContent(model)
+		if err != nil {
+			resp.Diagnostics.AddError("base64 decode failure", err.Error())
+			return
+		}
 	}
 
-	content, err = sopsEncrypt(ctx, model, content)
+	content, err := sopsEncrypt(newLogContext(ctx, model.Filename), model, content)
 	if err != nil {
-		resp.Diagnostics.AddError("failed to encrypt", err.Error())
+		addSopsErrors(&resp.Diagnostics, "failed to encrypt", err)
 		return
 	}
 
@@ -295,8 +497,21 @@ func (f fileResource) Create(ctx context.Context, req resource.CreateRequest, re
 		}
 	}
 
+	uid, gid, err := lookupOwner(model.Owner, model.Group)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to look up the owner of the file", err.Error())
+		return
+	}
+
+	unlock, err := lockFile(model.Filename)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to lock file", err.Error())
+		return
+	}
+	defer unlock()
+
 	fileMode, _ := strconv.ParseInt(model.FilePermission, 8, 64)
-	if err := os.WriteFile(model.Filename, content, os.FileMode(fileMode)); err != nil {
+	if err := writeFileAtomic(model.Filename, content, os.FileMode(fileMode), uid, gid); err != nil {
 		resp.Diagnostics.AddError("failed to write file", err.Error())
 		return
 	}
@@ -322,28 +537,103 @@ func resourceLocalFileContent(f fileResourceAPIModel) ([]byte, error) {
 	return []byte(f.Content), nil
 }
 
+// resourceValuesContent assembles the document built from secret_values and
+// public_values as JSON, and sets the regexes selecting the secret values
+func resourceValuesContent(tfm fileResourceModel, model *fileResourceAPIModel, diags *diag.Diagnostics) []byte {
+	for name, v := range map[string]attr.Value{
+		"content":           tfm.Content,
+		"sensitive_content": tfm.SensitiveContent,
+		"content_base64":    tfm.ContentBase64,
+		"source":            tfm.Source,
+		"encrypted_regex":   tfm.EncryptedRegex,
+		"input_type":        tfm.InputType,
+	} {
+		if !v.IsNull() {
+			diags.AddAttributeError(tfpath.Root(name), "Conflicting arguments", fmt.Sprintf("%s can't be combined with secret_values or public_values", name))
+		}
+	}
+	if diags.HasError() {
+		return nil
+	}
+
+	secret := valuesMap(tfm.SecretValues, "secret_values", diags)
+	public := valuesMap(tfm.PublicValues, "public_values", diags)
+	if diags.HasError() {
+		return nil
+	}
+
+	doc, encryptedRegex, unencryptedRegex, err := assembleValues(secret, public)
+	if err != nil {
+		diags.AddError("Invalid values", err.Error())
+		return nil
+	}
+	content, err := json.Marshal(doc)
+	if err != nil {
+		diags.AddError("Invalid values", err.Error())
+		return nil
+	}
+
+	model.InputType = "json"
+	model.EncryptedRegex = encryptedRegex
+	model.UnencryptedRegex = unencryptedRegex
+	return content
+}
+
+// valuesMap converts the value of a secret_values or public_values attribute
+func valuesMap(v types.Dynamic, name string, diags *diag.Diagnostics) map[string]interface{} {
+	value, err := fromDynamic(v)
+	if err != nil {
+		diags.AddAttributeError(tfpath.Root(name), "Invalid values", err.Error())
+		return nil
+	}
+	if value == nil {
+		return nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		diags.AddAttributeError(tfpath.Root(name), "Invalid values", fmt.Sprintf("%s must be a map or an object", name))
+		return nil
+	}
+	return m
+}
+
 func sopsEncrypt(ctx context.Context, fr fileResourceAPIModel, content []byte) ([]byte, error) {
-	inputStore := GetInputStore(fr.Filename)
-	outputStore := GetOutputStore(fr.Filename)
+	stores := fr.Stores
+	if stores == nil {
+		stores = defaultStoreConfig
+	}
+	inputStore := GetInputStore(stores, fr.Filename)
+	if fr.InputType != "" {
+		inputStore = storeForInputType(stores, fr.InputType)
+	}
+	outputStore := GetOutputStore(stores, fr.Filename)
+	if fr.OutputType != "" {
+		outputStore = storeForInputType(stores, fr.OutputType)
+	}
 	groups, err := KeyGroups(ctx, fr.EncryptConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	encrypt, err := Encrypt(EncryptOpts{
-		Cipher:         aes.NewCipher(),
-		InputStore:     inputStore,
-		OutputStore:    outputStore,
-		InputPath:      fr.Filename,
-		KeyServices:    LocalKeySvc(),
-		EncryptedRegex: "kms",
-		KeyGroups:      groups,
+	format := fr.OutputType
+	if format == "" {
+		format, _ = inputTypeForPath(fr.Filename)
+	}
+	start := time.Now()
+	encrypt, err := Encrypt(ctx, EncryptOpts{
+		Cipher:           aes.NewCipher(),
+		InputStore:       inputStore,
+		OutputStore:      outputStore,
+		InputPath:        fr.Filename,
+		KeyServices:      LocalKeySvc(),
+		EncryptedRegex:   fr.EncryptedRegex,
+		UnencryptedRegex: fr.UnencryptedRegex,
+		KeyGroups:        groups,
 	}, content)
-
+	logOperation(ctx, logEncrypt, format, keyGroupTypes(groups), start, err)
 	if err != nil {
 		return nil, err
 	}
-
 	return encrypt, nil
 }
 
@@ -367,3 +657,33 @@ func (v octalValidator) ValidateString(ctx context.Context, req validator.String
 		res.Diagnostics.AddAttributeError(req.Path, v.Description(ctx), "value must be a file mode")
 	}
 }
+
+// legacyDefault plans value for an unset attribute, unless the resource
+// exists with legacy, an earlier default, which it keeps. Unlike a static
+// default, value can change without planning changes to existing resources.
+// Any other prior value, such as one from an attribute that is no longer set,
+// is replaced by value. Terraform plans replaced resources without a prior
+// state, so they get value as well.
+type legacyDefault struct {
+	value  string
+	legacy string
+}
+
+func (m legacyDefault) Description(_ context.Context) string {
+	return fmt.Sprintf("defaults to %q, existing resources keep %q", m.value, m.legacy)
+}
+
+func (m legacyDefault) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m legacyDefault) PlanModifyString(_ context.Context, req planmodifier.StringRequest, res *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+	if req.StateValue.ValueString() == m.legacy {
+		res.PlanValue = req.StateValue
+		return
+	}
+	res.PlanValue = types.StringValue(m.value)
+}
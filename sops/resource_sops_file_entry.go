@@ -0,0 +1,395 @@
+package sops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.ResourceWithConfigure = &fileEntryResource{}
+
+func newFileEntryResource() resource.Resource {
+	return &fileEntryResource{}
+}
+
+type fileEntryResource struct {
+	stores *config.StoresConfig
+}
+
+type fileEntryResourceModel struct {
+	Filename  types.String `tfsdk:"filename"`
+	Path      types.String `tfsdk:"path"`
+	Value     types.String `tfsdk:"value"`
+	InputType types.String `tfsdk:"input_type"`
+	ID        types.String `tfsdk:"id"`
+}
+
+func (r *fileEntryResource) Configure(_ context.Context, req resource.ConfigureRequest, _ *resource.ConfigureResponse) {
+	r.stores = configuredStores(req.ProviderData)
+}
+
+func (r *fileEntryResource) Metadata(_ context.Context, _ resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = "sops_file_entry"
+}
+
+func (r *fileEntryResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Set a single value in an existing sops-encrypted file, like sops set",
+		Attributes: map[string]schema.Attribute{
+			"filename": schema.StringAttribute{
+				Description: "Path to the encrypted file",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"path": schema.StringAttribute{
+				Description: "Dot-separated keys of the value in the file, e.g. db.password. Dots and backslashes in keys are escaped with a backslash",
+				Required:    true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"value": schema.StringAttribute{
+				Description: "Value to set",
+				Required:    true,
+				Sensitive:   true,
+			},
+			"input_type": schema.StringAttribute{
+				Description: "Type of the file: json, yaml, dotenv, ini. Detected from the file extension or the sops metadata by default",
+				Optional:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *fileEntryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan fileEntryResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	filename, path := plan.Filename.ValueString(), plan.Path.ValueString()
+	if err := setFileEntry(newLogContext(ctx, filename), filename, plan.InputType, r.stores, path, plan.Value.ValueString()); err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("failed to set %s in %s", path, filename), err.Error())
+		return
+	}
+
+	plan.ID = types.StringValue(filename + ":" + path)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *fileEntryResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state fileEntryResourceModel
+	if ds := req.State.Get(ctx, &state); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	filename, path := state.Filename.ValueString(), state.Path.ValueString()
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		resp.Diagnostics.AddWarning("file not found", fmt.Sprintf("%q does not exist", filename))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	value, found, err := readFileEntry(newLogContext(ctx, filename), filename, state.InputType, r.stores, path)
+	if err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("failed to read %s from %s", path, filename), err.Error())
+		return
+	}
+	if !found {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	state.Value = value
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *fileEntryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan fileEntryResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	filename, path := plan.Filename.ValueString(), plan.Path.ValueString()
+	if err := setFileEntry(newLogContext(ctx, filename), filename, plan.InputType, r.stores, path, plan.Value.ValueString()); err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("failed to set %s in %s", path, filename), err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *fileEntryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state fileEntryResourceModel
+	if ds := req.State.Get(ctx, &state); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	filename, path := state.Filename.ValueString(), state.Path.ValueString()
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return
+	}
+	if err := unsetFileEntry(newLogContext(ctx, filename), filename, state.InputType, r.stores, path); err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("failed to unset %s in %s", path, filename), err.Error())
+	}
+}
+
+// splitEntryPath splits a dot-separated path into keys. A backslash escapes
+// the next character, as keys are escaped by escape_keys.
+func splitEntryPath(path string) ([]string, error) {
+	return splitKeyPath(path, defaultKeySeparator, true)
+}
+
+// treePath converts keys to a sops tree path, using list indexes for the
+// keys that address items of existing lists. Keys below a value that is
+// neither a map nor a list are an error, as sops can't address them.
+func treePath(branch mozillasops.TreeBranch, keys []string) ([]interface{}, error) {
+	path := make([]interface{}, len(keys))
+	var current interface{} = branch
+	for i, key := range keys {
+		switch typed := current.(type) {
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("%q is not a list index", key)
+			}
+			path[i] = idx
+			current = nil
+			if idx < len(typed) {
+				current = typed[idx]
+			}
+		case mozillasops.TreeBranch:
+			path[i] = key
+			current = nil
+			for _, item := range typed {
+				if item.Key == key {
+					current = item.Value
+					break
+				}
+			}
+		case nil:
+			// The parent doesn't exist yet, and is created as a map
+			path[i] = key
+		default:
+			return nil, fmt.Errorf("%q is neither a map nor a list, it has no key %q", keys[i-1], key)
+		}
+	}
+	return path, nil
+}
+
+// readFileEntry decrypts an encrypted file and returns the value at path.
+// found is false if the file has no value at path.
+func readFileEntry(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig, path string) (value types.String, found bool, err error) {
+	keys, err := splitEntryPath(path)
+	if err != nil {
+		return types.StringNull(), false, err
+	}
+	tree, _, _, err := loadEntryTree(ctx, filename, inputType, stores)
+	if err != nil {
+		return types.StringNull(), false, err
+	}
+
+	p, err := treePath(tree.Branches[0], keys)
+	if err != nil {
+		return types.StringNull(), false, nil
+	}
+	leaf, err := tree.Branches[0].Truncate(p)
+	if err != nil {
+		return types.StringNull(), false, nil
+	}
+
+	switch leaf := leaf.(type) {
+	case mozillasops.TreeBranch, []interface{}:
+		// The path now holds a structure, which can't match any value
+		return types.StringNull(), true, nil
+	case nil:
+		return types.StringValue("null"), true, nil
+	default:
+		return types.StringValue(fmt.Sprint(leaf)), true, nil
+	}
+}
+
+// setFileEntry sets the value at path in an encrypted file, encrypting it
+// with the data key of the file. The value keeps the type of the value it
+// replaces, see entryValue.
+func setFileEntry(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig, path, value string) error {
+	keys, err := splitEntryPath(path)
+	if err != nil {
+		return err
+	}
+	return editFileEntries(ctx, filename, inputType, stores, func(tree *mozillasops.Tree) error {
+		p, err := treePath(tree.Branches[0], keys)
+		if err != nil {
+			return err
+		}
+		existing, _ := tree.Branches[0].Truncate(p)
+		typed, err := entryValue(existing, value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", path, err)
+		}
+		tree.Branches[0], _ = tree.Branches[0].Set(p, typed)
+		return nil
+	})
+}
+
+// entryValue converts value to the type of existing, so that setting an
+// integer, float or boolean doesn't turn it into a string. Other values,
+// including new ones, are set as strings.
+func entryValue(existing interface{}, value string) (interface{}, error) {
+	switch existing.(type) {
+	case int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("the existing value is an integer, got %q", value)
+		}
+		return i, nil
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("the existing value is a float, got %q", value)
+		}
+		return f, nil
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("the existing value is a boolean, got %q", value)
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// unsetFileEntry removes the value at path from an encrypted file. It is not
+// an error if the file has no value at path.
+func unsetFileEntry(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig, path string) error {
+	keys, err := splitEntryPath(path)
+	if err != nil {
+		return err
+	}
+	return editFileEntries(ctx, filename, inputType, stores, func(tree *mozillasops.Tree) error {
+		p, err := treePath(tree.Branches[0], keys)
+		if err != nil {
+			return nil
+		}
+		branch, err := tree.Branches[0].Unset(p)
+		if err != nil {
+			var notFound *mozillasops.SopsKeyNotFound
+			if errors.As(err, &notFound) {
+				return nil
+			}
+			return err
+		}
+		tree.Branches[0] = branch
+		return nil
+	})
+}
+
+// editFileEntries decrypts an encrypted file, applies edit to its first
+// document and encrypts it again with the same data key, like sops set
+func editFileEntries(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig, edit func(*mozillasops.Tree) error) error {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tree, format, dataKey, err := loadEntryTree(ctx, filename, inputType, stores)
+	if err != nil {
+		return err
+	}
+
+	if err := edit(&tree); err != nil {
+		return err
+	}
+	start := time.Now()
+	err = common.EncryptTree(common.EncryptTreeOpts{
+		DataKey: dataKey,
+		Tree:    &tree,
+		Cipher:  aes.NewCipher(),
+	})
+	logOperation(ctx, logEncrypt, format, keyGroupTypes(tree.Metadata.KeyGroups), start, err)
+	if err != nil {
+		return err
+	}
+
+	edited, err := storeForInputType(stores, format).EmitEncryptedFile(tree)
+	if err != nil {
+		return fmt.Errorf("Could not marshal tree: %s", err)
+	}
+	return replaceFile(filename, edited)
+}
+
+// loadEntryTree loads and decrypts an encrypted file, returning the tree
+// along with the format of the file and its data key
+func loadEntryTree(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig) (mozillasops.Tree, string, []byte, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return mozillasops.Tree{}, "", nil, err
+	}
+	format, err := resolveInputType(filename, content, inputType)
+	if err != nil {
+		return mozillasops.Tree{}, "", nil, err
+	}
+	if err := validateInputType(format); err != nil {
+		return mozillasops.Tree{}, "", nil, err
+	}
+	if parsers[format].store == "binary" {
+		return mozillasops.Tree{}, "", nil, fmt.Errorf("Files of type %s are encrypted as a whole, their entries can't be edited", format)
+	}
+
+	store := storeForInputType(stores, format)
+	tree, err := store.LoadEncryptedFile(content)
+	if err != nil {
+		return mozillasops.Tree{}, "", nil, err
+	}
+	if len(tree.Branches) == 0 {
+		return mozillasops.Tree{}, "", nil, fmt.Errorf("File has no documents")
+	}
+
+	start := time.Now()
+	recorder := newKeyServiceRecorder(ctx, "decrypt")
+	dataKey, err := common.DecryptTree(common.DecryptTreeOpts{
+		Cipher:      aes.NewCipher(),
+		Tree:        &tree,
+		KeyServices: recorder.wrap(LocalKeySvc()),
+	})
+	logOperation(ctx, logDecrypt, format, recorder.keyType(), start, err)
+	if err != nil {
+		return mozillasops.Tree{}, "", nil, err
+	}
+	return tree, format, dataKey, nil
+}
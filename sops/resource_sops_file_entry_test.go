@@ -0,0 +1,234 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestSplitEntryPath(t *testing.T) {
+	for path, expected := range map[string][]string{
+		"password":       {"password"},
+		"db.password":    {"db", "password"},
+		`a\.b.c`:         {"a.b", "c"},
+		`a\\.b`:          {`a\`, "b"},
+		"a_list.0.value": {"a_list", "0", "value"},
+	} {
+		actual, err := splitEntryPath(path)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", path, err)
+			continue
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%q: expected %q, got %q", path, expected, actual)
+		}
+	}
+
+	for _, path := range []string{"a..b", ".a", "a.", `a\`} {
+		if _, err := splitEntryPath(path); err == nil {
+			t.Errorf("%q: expected an error", path)
+		}
+	}
+}
+
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("test-fixtures", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func readFixtureData(t *testing.T, filename string) map[string]string {
+	t.Helper()
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := readData(context.Background(), content, "yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestFileEntry(t *testing.T) {
+	filename := copyFixture(t, "complex-list.yaml")
+	expected := readFixtureData(t, filename)
+
+	for _, tc := range []struct {
+		path, value string
+	}{
+		{"a_list.1.name", "baz"},
+		{"a_list.0.value", "qux"},
+		{"db.password", "secret"},
+		{"a_list.2.name", "new"},
+	} {
+		if err := setFileEntry(context.Background(), filename, types.StringNull(), nil, tc.path, tc.value); err != nil {
+			t.Fatalf("%s: %s", tc.path, err)
+		}
+		expected[tc.path] = tc.value
+
+		value, found, err := readFileEntry(context.Background(), filename, types.StringNull(), nil, tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || value.ValueString() != tc.value {
+			t.Errorf("%s: expected %q, got %q (found: %t)", tc.path, tc.value, value.ValueString(), found)
+		}
+	}
+	if actual := readFixtureData(t, filename); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	if err := unsetFileEntry(context.Background(), filename, types.StringNull(), nil, "db.password"); err != nil {
+		t.Fatal(err)
+	}
+	delete(expected, "db.password")
+	if err := unsetFileEntry(context.Background(), filename, types.StringNull(), nil, "db.user"); err != nil {
+		t.Errorf("Expected unsetting a missing entry to succeed, got %s", err)
+	}
+	if _, found, err := readFileEntry(context.Background(), filename, types.StringNull(), nil, "db.password"); err != nil || found {
+		t.Errorf("Expected the entry to be removed, got found: %t, error: %v", found, err)
+	}
+	if actual := readFixtureData(t, filename); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %s", info.Mode())
+	}
+}
+
+func TestFileEntry_scalarParent(t *testing.T) {
+	filename := copyFixture(t, "complex-list.yaml")
+	if err := setFileEntry(context.Background(), filename, types.StringNull(), nil, "a", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	expected := readFixtureData(t, filename)
+
+	for _, path := range []string{"a.b", "a_list.0.name.first"} {
+		if _, found, err := readFileEntry(context.Background(), filename, types.StringNull(), nil, path); err != nil || found {
+			t.Errorf("%s: expected no entry, got found: %t, error: %v", path, found, err)
+		}
+		if err := setFileEntry(context.Background(), filename, types.StringNull(), nil, path, "bar"); err == nil {
+			t.Errorf("%s: expected setting a key of a scalar to fail", path)
+		}
+		if err := unsetFileEntry(context.Background(), filename, types.StringNull(), nil, path); err != nil {
+			t.Errorf("%s: expected unsetting a missing entry to succeed, got %s", path, err)
+		}
+	}
+	if actual := readFixtureData(t, filename); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected the file to be unchanged, got %v", actual)
+	}
+}
+
+func TestFileEntry_types(t *testing.T) {
+	filename := copyFixture(t, "basic.yaml")
+	for _, tc := range []struct {
+		path, value string
+		expected    interface{}
+	}{
+		{"integer", "7", 7},
+		{"float", "2.5", 2.5},
+		{"bool", "false", false},
+		{"hello", "42", "42"},
+		{"new", "true", "true"},
+	} {
+		if err := setFileEntry(context.Background(), filename, types.StringNull(), nil, tc.path, tc.value); err != nil {
+			t.Fatalf("%s: %s", tc.path, err)
+		}
+		tree, _, _, err := loadEntryTree(context.Background(), filename, types.StringNull(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := tree.Branches[0].Truncate([]interface{}{tc.path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.expected, leaf) {
+			t.Errorf("%s: expected %#v, got %#v", tc.path, tc.expected, leaf)
+		}
+	}
+
+	if err := setFileEntry(context.Background(), filename, types.StringNull(), nil, "integer", "seven"); err == nil {
+		t.Error("Expected an error setting an integer to a string")
+	}
+}
+
+func TestFileEntry_binary(t *testing.T) {
+	filename := copyFixture(t, "secrets.toml")
+	if err := setFileEntry(context.Background(), filename, types.StringNull(), nil, "password", "secret"); err == nil {
+		t.Error("Expected an error editing a file encrypted in binary mode")
+	}
+}
+
+const configTestResourceSopsFileEntry_basic = `
+resource "sops_file_entry" "test" {
+  filename = "%s"
+  path     = "db.password"
+  value    = "%s"
+}`
+
+func TestResourceSopsFileEntry(t *testing.T) {
+	filename := copyFixture(t, "nested.yaml")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFileEntry_basic, filename, "baz"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sops_file_entry.test", "value", "baz"),
+					checkFileEntry(filename, "db.password", "baz"),
+					checkFileEntry(filename, "db.user", "foo"),
+				),
+			},
+			{
+				// Changing the value outside of Terraform is detected as drift
+				PreConfig: func() {
+					if err := setFileEntry(context.Background(), filename, types.StringNull(), nil, "db.password", "changed"); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: fmt.Sprintf(configTestResourceSopsFileEntry_basic, filename, "baz"),
+				Check:  checkFileEntry(filename, "db.password", "baz"),
+			},
+		},
+		CheckDestroy: func(*terraform.State) error {
+			if _, found, err := readFileEntry(context.Background(), filename, types.StringNull(), nil, "db.password"); err != nil || found {
+				return fmt.Errorf("expected the entry to be removed, found: %t, error: %v", found, err)
+			}
+			return nil
+		},
+	})
+}
+
+func checkFileEntry(filename, path, expected string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		value, found, err := readFileEntry(context.Background(), filename, types.StringNull(), nil, path)
+		if err != nil {
+			return err
+		}
+		if !found || value.ValueString() != expected {
+			return fmt.Errorf("expected %s to be %q, got %q", path, expected, value.ValueString())
+		}
+		return nil
+	}
+}
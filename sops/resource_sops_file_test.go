@@ -1,12 +1,24 @@
 package sops
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 const configTestResourceSopsFile_emptyContentYaml = `
@@ -86,3 +98,288 @@ func TestResourceSopsFile_ProviderAndResourceConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestSopsEncrypt_encryptedRegex(t *testing.T) {
+	model := fileResourceAPIModel{
+		Filename: "secrets.json",
+		EncryptConfig: encryptConfigModel{
+			EncryptionProvider: "pgp",
+			Pgp:                PgpConf{Fingerprint: "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"},
+		},
+	}
+	content := []byte(`{"password": "secret-value", "user": "public-value"}`)
+
+	encrypted, err := sopsEncrypt(context.Background(), model, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"secret-value", "public-value"} {
+		if strings.Contains(string(encrypted), value) {
+			t.Errorf("Expected all values to be encrypted by default, found %q", value)
+		}
+	}
+
+	model.EncryptedRegex = "^password$"
+	encrypted, err = sopsEncrypt(context.Background(), model, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encrypted), "secret-value") || !strings.Contains(string(encrypted), "public-value") {
+		t.Errorf("Expected only the values matching encrypted_regex to be encrypted, got %s", encrypted)
+	}
+}
+
+func TestSopsEncrypt_conversion(t *testing.T) {
+	tc := []struct {
+		name       string
+		inputType  string
+		outputType string
+		content    string
+		expected   map[string]string
+	}{
+		{"json to yaml", "json", "yaml", `{"hello": "world", "db": {"password": "foo"}}`, map[string]string{"hello": "world", "db.password": "foo"}},
+		{"json to dotenv", "json", "dotenv", `{"HELLO": "world"}`, map[string]string{"HELLO": "world"}},
+		{"dotenv to json", "dotenv", "json", "HELLO=world\n", map[string]string{"HELLO": "world"}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			model := fileResourceAPIModel{
+				Filename:   "secrets.txt",
+				InputType:  c.inputType,
+				OutputType: c.outputType,
+				EncryptConfig: encryptConfigModel{
+					EncryptionProvider: "pgp",
+					Pgp:                PgpConf{Fingerprint: "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"},
+				},
+			}
+			encrypted, err := sopsEncrypt(context.Background(), model, []byte(c.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _, err := readData(context.Background(), encrypted, c.outputType, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, data) {
+				t.Errorf("Unexpected data, expected %v, got %v", c.expected, data)
+			}
+		})
+	}
+}
+
+const configTestResourceSopsFile_values = `
+resource "sops_file" "values" {
+  filename = "%s/values.enc.yaml"
+  secret_values = {
+    db = {
+      password = "s3cr3t"
+    }
+  }
+  public_values = {
+    db = {
+      host = "db.example"
+    }
+  }
+  pgp {
+	fingerprint = "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"
+  }
+}`
+
+func TestResourceSopsFile_values(t *testing.T) {
+	dir := t.TempDir()
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_values, dir),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("sops_file.values", "id"),
+					func(*terraform.State) error {
+						content, err := os.ReadFile(dir + "/values.enc.yaml")
+						if err != nil {
+							return err
+						}
+						if !strings.Contains(string(content), "host: db.example") || strings.Contains(string(content), "s3cr3t") {
+							return fmt.Errorf("Unexpected encrypted file:\n%s", content)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestSopsEncrypt_stores(t *testing.T) {
+	stores := (&storesConfigModel{
+		JSON: &storeConfigModel{Indent: types.Int64Value(4)},
+		YAML: &storeConfigModel{Indent: types.Int64Value(2)},
+	}).storesConfig()
+	model := fileResourceAPIModel{
+		Filename:  "secrets.yaml",
+		InputType: "json",
+		Stores:    stores,
+		EncryptConfig: encryptConfigModel{
+			EncryptionProvider: "pgp",
+			Pgp:                PgpConf{Fingerprint: "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"},
+		},
+	}
+	encrypted, err := sopsEncrypt(context.Background(), model, []byte(`{"db": {"password": "foo"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(encrypted), "db:\n  password: ENC[") {
+		t.Errorf("Expected YAML indented with 2 spaces, got:\n%s", encrypted)
+	}
+
+	// Decrypted content is emitted by the configured stores as well
+	_, raw, err := readData(context.Background(), encrypted, "yaml", stores)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != "db:\n  password: foo\n" {
+		t.Errorf("Expected YAML indented with 2 spaces, got:\n%s", raw)
+	}
+
+	model.Filename = "secrets.json"
+	encrypted, err = sopsEncrypt(context.Background(), model, []byte(`{"db": {"password": "foo"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(encrypted), "{\n    \"db\": {\n        \"password\": \"ENC[") {
+		t.Errorf("Expected JSON indented with 4 spaces, got:\n%s", encrypted)
+	}
+}
+
+func TestFileResource_UpgradeStateV0(t *testing.T) {
+	ctx := context.Background()
+	r := fileResource{}
+	upgrader := r.UpgradeState(ctx)[0]
+
+	priorType := upgrader.PriorSchema.Type().TerraformType(ctx).(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(priorType.AttributeTypes))
+	for name, typ := range priorType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["filename"] = tftypes.NewValue(tftypes.String, "secrets.yaml")
+	values["file_permission"] = tftypes.NewValue(tftypes.String, "0777")
+
+	var current fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &current)
+	req := fwresource.UpgradeStateRequest{
+		State: &tfsdk.State{Schema: *upgrader.PriorSchema, Raw: tftypes.NewValue(priorType, values)},
+	}
+	resp := fwresource.UpgradeStateResponse{State: tfsdk.State{Schema: current.Schema}}
+	upgrader.StateUpgrader(ctx, req, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+
+	var model fileResourceModel
+	if ds := resp.State.Get(ctx, &model); ds.HasError() {
+		t.Fatal(ds)
+	}
+	if model.FilePermission.ValueString() != "0777" {
+		t.Errorf("Expected file_permission to be kept, got %s", model.FilePermission)
+	}
+	if !model.Owner.IsNull() || !model.Group.IsNull() || !model.DestroyBehavior.IsNull() {
+		t.Errorf("Expected the new attributes to be null, got %s, %s and %s", model.Owner, model.Group, model.DestroyBehavior)
+	}
+}
+
+func TestLegacyDefault(t *testing.T) {
+	for _, tc := range []struct {
+		config, state types.String
+		expected      string
+	}{
+		// New or replaced resources
+		{types.StringNull(), types.StringNull(), "0600"},
+		{types.StringNull(), types.StringValue("0777"), "0777"},
+		{types.StringValue("0640"), types.StringValue("0777"), "0640"},
+		// file_permission was removed from the configuration
+		{types.StringNull(), types.StringValue("0640"), "0600"},
+	} {
+		req := planmodifier.StringRequest{ConfigValue: tc.config, StateValue: tc.state, PlanValue: tc.config}
+		resp := planmodifier.StringResponse{PlanValue: tc.config}
+		legacyDefault{value: "0600", legacy: "0777"}.PlanModifyString(context.Background(), req, &resp)
+		if resp.PlanValue.ValueString() != tc.expected {
+			t.Errorf("config %s, state %s: expected %s, got %s", tc.config, tc.state, tc.expected, resp.PlanValue)
+		}
+	}
+}
+
+func TestArchiveFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	if err := os.WriteFile(filename, []byte("encrypted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	archived, err := archiveFile(filename, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filename + ".20240301T123000Z.bak"; archived != expected {
+		t.Errorf("Expected the file to be archived to %s, got %s", expected, archived)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be moved, got %v", err)
+	}
+	if content, err := os.ReadFile(archived); err != nil || string(content) != "encrypted" {
+		t.Errorf("Expected the archive to hold the file, got %q, %v", content, err)
+	}
+
+	if archived, err := archiveFile(filename, now); err != nil || archived != "" {
+		t.Errorf("Expected archiving a missing file to do nothing, got %q, %v", archived, err)
+	}
+}
+
+func TestFileResource_DeleteMissingDir(t *testing.T) {
+	ctx := context.Background()
+	r := fileResource{}
+	var current fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &current)
+
+	filename := filepath.Join(t.TempDir(), "removed", "secrets.enc.yaml")
+	for _, behavior := range []string{destroyBehaviorDelete, destroyBehaviorArchive} {
+		state := tfsdk.State{Schema: current.Schema}
+		state.Raw = tftypes.NewValue(current.Schema.Type().TerraformType(ctx), nil)
+		state.SetAttribute(ctx, tfpath.Root("filename"), filename)
+		state.SetAttribute(ctx, tfpath.Root("destroy_behavior"), behavior)
+
+		resp := fwresource.DeleteResponse{State: state}
+		r.Delete(ctx, fwresource.DeleteRequest{State: state}, &resp)
+		if resp.Diagnostics.HasError() {
+			t.Errorf("%s: expected a file in a missing directory to count as deleted, got %v", behavior, resp.Diagnostics)
+		}
+	}
+}
+
+const configTestResourceSopsFile_retain = `
+resource "sops_file" "x" {
+  content          = "{\"hello\": \"world\"}"
+  filename         = "%s"
+  destroy_behavior = "retain"
+  pgp {
+	fingerprint = "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"
+  }
+}`
+
+func TestResourceSopsFile_retain(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "retained.json")
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_retain, filename),
+				Check:  resource.TestCheckResourceAttr("sops_file.x", "destroy_behavior", "retain"),
+			},
+		},
+		CheckDestroy: func(*terraform.State) error {
+			if _, err := os.Stat(filename); err != nil {
+				return fmt.Errorf("expected the file to be retained: %s", err)
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,440 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/config"
+	"github.com/getsops/sops/v3/pgp"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.ResourceWithConfigure  = &recipientsResource{}
+	_ resource.ResourceWithModifyPlan = &recipientsResource{}
+)
+
+func newRecipientsResource() resource.Resource {
+	return &recipientsResource{}
+}
+
+type recipientsResource struct {
+	rootEncryptConfig encryptConfigModel
+	stores            *config.StoresConfig
+}
+
+type recipientsResourceModel struct {
+	Filename   types.String `tfsdk:"filename"`
+	InputType  types.String `tfsdk:"input_type"`
+	Recipients types.Set    `tfsdk:"recipients"`
+	ID         types.String `tfsdk:"id"`
+
+	Kms types.Object `tfsdk:"kms"`
+	Pgp types.Object `tfsdk:"pgp"`
+}
+
+func (r *recipientsResource) Configure(_ context.Context, req resource.ConfigureRequest, _ *resource.ConfigureResponse) {
+	pd, _ := req.ProviderData.(providerData)
+	r.rootEncryptConfig = pd.encryptConfig
+	r.stores = configuredStores(req.ProviderData)
+}
+
+func (r *recipientsResource) Metadata(_ context.Context, _ resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = "sops_recipients"
+}
+
+func (r *recipientsResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manage the master keys of an existing sops-encrypted file, like sops updatekeys",
+		Attributes: map[string]schema.Attribute{
+			"filename": schema.StringAttribute{
+				Description: "Path to the encrypted file",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"input_type": schema.StringAttribute{
+				Description: "Type of the file: json, yaml, dotenv, ini, toml, properties, hcl, raw. Detected from the file extension or the sops metadata by default",
+				Optional:    true,
+			},
+
+			"recipients": schema.SetAttribute{
+				Description: "Master keys the data key of the file is encrypted for",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"kms": schema.SingleNestedBlock{
+				Attributes: map[string]schema.Attribute{
+					"arn": schema.StringAttribute{
+						Description: "The ARN of the KMS key",
+						Optional:    true,
+					},
+					"profile": schema.StringAttribute{
+						Description: "The AWS Profile to use when retrieving the key",
+						Optional:    true,
+						Computed:    true,
+						Default:     stringdefault.StaticString(""),
+					},
+				},
+			},
+			"pgp": schema.SingleNestedBlock{
+				Attributes: map[string]schema.Attribute{
+					"fingerprint": schema.StringAttribute{
+						Description: "The Fingerprint of the PGP key",
+						Optional:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// ModifyPlan plans the recipients from the configured master keys, so the
+// plan shows the keys that are added and removed
+func (r *recipientsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan recipientsResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+	if !isKnownBlock(plan.Kms) || !isKnownBlock(plan.Pgp) {
+		return
+	}
+
+	group, ds := r.keyGroup(ctx, plan)
+	if ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+	if plan.Filename.IsUnknown() || plan.InputType.IsUnknown() {
+		return
+	}
+	// The keys of other types in the file are kept
+	filename := plan.Filename.ValueString()
+	groups, err := fileKeyGroups(filename, plan.InputType, r.stores)
+	if err != nil {
+		// The file may be created by the same apply
+		return
+	}
+	group, err = mergeKeyGroup(groups, group, recipientKeyTypes)
+	if err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("failed to update the master keys of %s", filename), err.Error())
+		return
+	}
+	recipients, ds := types.SetValueFrom(ctx, types.StringType, recipientIDs([]mozillasops.KeyGroup{group}))
+	if ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, tfpath.Root("recipients"), recipients)...)
+}
+
+func (r *recipientsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan recipientsResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	r.apply(ctx, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	plan.ID = types.StringValue(plan.Filename.ValueString())
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *recipientsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state recipientsResourceModel
+	if ds := req.State.Get(ctx, &state); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	filename := state.Filename.ValueString()
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		resp.Diagnostics.AddWarning("file not found", fmt.Sprintf("%q does not exist", filename))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	groups, err := fileKeyGroups(filename, state.InputType, r.stores)
+	if err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("failed to read the master keys of %s", filename), err.Error())
+		return
+	}
+	recipients, ds := types.SetValueFrom(ctx, types.StringType, recipientIDs(groups))
+	if ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+	state.Recipients = recipients
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *recipientsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan recipientsResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	r.apply(ctx, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete leaves the file and its master keys in place, they aren't managed
+// by this resource alone
+func (r *recipientsResource) Delete(context.Context, resource.DeleteRequest, *resource.DeleteResponse) {
+}
+
+// apply re-encrypts the data key of the file for the configured master keys
+// and records the resulting recipients in the model
+func (r *recipientsResource) apply(ctx context.Context, m *recipientsResourceModel, diags *diag.Diagnostics) {
+	group, ds := r.keyGroup(ctx, *m)
+	if ds.HasError() {
+		diags.Append(ds...)
+		return
+	}
+
+	filename := m.Filename.ValueString()
+	groups, err := rekeyFile(newLogContext(ctx, filename), filename, m.InputType, r.stores, group, recipientKeyTypes)
+	if err != nil {
+		diags.AddError(fmt.Sprintf("failed to update the master keys of %s", filename), err.Error())
+		return
+	}
+
+	recipients, ds := types.SetValueFrom(ctx, types.StringType, recipientIDs(groups))
+	if ds.HasError() {
+		diags.Append(ds...)
+		return
+	}
+	m.Recipients = recipients
+}
+
+// keyGroup returns the master keys configured on the resource, or on the
+// provider if the resource has none
+func (r *recipientsResource) keyGroup(ctx context.Context, m recipientsResourceModel) (mozillasops.KeyGroup, diag.Diagnostics) {
+	var (
+		ds      diag.Diagnostics
+		configs []encryptConfigModel
+	)
+
+	if !m.Kms.IsNull() {
+		cfg := encryptConfigModel{EncryptionProvider: "kms"}
+		if ds.Append(unmarshalKmsConf(ctx, m.Kms, &cfg.Kms)...); ds.HasError() {
+			return nil, ds
+		}
+		configs = append(configs, cfg)
+	}
+	if !m.Pgp.IsNull() {
+		cfg := encryptConfigModel{EncryptionProvider: "pgp"}
+		if ds.Append(unmarshalPgpConf(ctx, m.Pgp, &cfg.Pgp)...); ds.HasError() {
+			return nil, ds
+		}
+		configs = append(configs, cfg)
+	}
+	if len(configs) == 0 && r.rootEncryptConfig.EncryptionProvider != "" {
+		configs = append(configs, r.rootEncryptConfig)
+	}
+	if len(configs) == 0 {
+		ds.AddError(
+			"encryption is unconfigured",
+			fmt.Sprintf(
+				"an encryption provider (%s) must be specified on the resource if not provided on the provider",
+				strings.Join([]string{"kms", "pgp"}, " "),
+			),
+		)
+		return nil, ds
+	}
+
+	var group mozillasops.KeyGroup
+	for _, cfg := range configs {
+		groups, err := KeyGroups(ctx, cfg)
+		if err != nil {
+			ds.AddError("invalid encryption configuration", err.Error())
+			return nil, ds
+		}
+		group = append(group, groups[0]...)
+	}
+	return group, ds
+}
+
+// isKnownBlock reports whether a block and all its attributes are known
+func isKnownBlock(o types.Object) bool {
+	if o.IsUnknown() {
+		return false
+	}
+	for _, v := range o.Attributes() {
+		if v.IsUnknown() {
+			return false
+		}
+	}
+	return true
+}
+
+// recipientIDs returns the sorted identifiers of the master keys in groups.
+// PGP fingerprints are compared case-insensitively by sops, so they're
+// upper-cased to avoid spurious differences.
+func recipientIDs(groups []mozillasops.KeyGroup) []string {
+	ids := []string{}
+	for _, group := range groups {
+		for _, key := range group {
+			id := key.ToString()
+			if _, ok := key.(*pgp.MasterKey); ok {
+				id = strings.ToUpper(id)
+			}
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// recipientKeyTypes are the types of the master keys sops_recipients manages,
+// as named by MasterKey.TypeToIdentifier
+var recipientKeyTypes = map[string]bool{
+	"kms": true,
+	"pgp": true,
+}
+
+// mergeKeyGroup returns the keys of group along with the keys of groups, the
+// key groups of a file, that aren't of a managed type. Files with several
+// key groups, as used for Shamir secret sharing, are rejected, as it is
+// ambiguous which group the keys belong to.
+func mergeKeyGroup(groups []mozillasops.KeyGroup, group mozillasops.KeyGroup, managed map[string]bool) (mozillasops.KeyGroup, error) {
+	if len(groups) > 1 {
+		return nil, fmt.Errorf("The file has %d key groups, only files with a single key group are supported. Update the keys of files using Shamir secret sharing with sops", len(groups))
+	}
+	merged := append(mozillasops.KeyGroup{}, group...)
+	for _, g := range groups {
+		for _, key := range g {
+			if !managed[key.TypeToIdentifier()] {
+				merged = append(merged, key)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// fileKeyGroups returns the key groups from the metadata of an encrypted file
+func fileKeyGroups(filename string, inputType types.String, stores *config.StoresConfig) ([]mozillasops.KeyGroup, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	format, err := resolveInputType(filename, content, inputType)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateInputType(format); err != nil {
+		return nil, err
+	}
+
+	tree, err := storeForInputType(stores, format).LoadEncryptedFile(content)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Metadata.KeyGroups, nil
+}
+
+// rekeyFile encrypts the data key of a file for the master keys in group, in
+// place, returning the key groups written to the file. Only the keys of the
+// managed types are replaced, see mergeKeyGroup.
+func rekeyFile(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig, group mozillasops.KeyGroup, managed map[string]bool) ([]mozillasops.KeyGroup, error) {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := resolveInputType(filename, content, inputType)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateInputType(format); err != nil {
+		return nil, err
+	}
+
+	rekeyed, groups, err := rekeyData(ctx, content, format, storeForInputType(stores, format), group, managed)
+	if err != nil {
+		return nil, err
+	}
+	if err := replaceFile(filename, rekeyed); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// rekeyData encrypts the current data key of content for the master keys in
+// group instead of its current keys of the managed types, like sops
+// updatekeys. The encrypted values are left untouched.
+func rekeyData(ctx context.Context, content []byte, format string, store common.Store, group mozillasops.KeyGroup, managed map[string]bool) ([]byte, []mozillasops.KeyGroup, error) {
+	tree, err := store.LoadEncryptedFile(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	group, err = mergeKeyGroup(tree.Metadata.KeyGroups, group, managed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	recorder := newKeyServiceRecorder(ctx, "decrypt")
+	dataKey, err := tree.Metadata.GetDataKeyWithKeyServices(recorder.wrap(LocalKeySvc()), nil)
+	logOperation(ctx, logDecrypt, format, recorder.keyType(), start, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree.Metadata.KeyGroups = []mozillasops.KeyGroup{group}
+	start = time.Now()
+	recorder = newKeyServiceRecorder(ctx, "encrypt")
+	if errs := tree.Metadata.UpdateMasterKeysWithKeyServices(dataKey, recorder.wrap(LocalKeySvc())); len(errs) > 0 {
+		err = fmt.Errorf("Could not encrypt the data key: %s", errs)
+	}
+	logOperation(ctx, logEncrypt, format, keyGroupTypes(tree.Metadata.KeyGroups), start, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rekeyed, err := store.EmitEncryptedFile(tree)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not marshal tree: %s", err)
+	}
+	return rekeyed, tree.Metadata.KeyGroups, nil
+}
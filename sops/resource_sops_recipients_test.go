@@ -0,0 +1,163 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"filippo.io/age"
+	mozillasops "github.com/getsops/sops/v3"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/pgp"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const testPgpFingerprint = "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"
+
+func TestRekeyFile(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "basic.yaml")
+	if err := os.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(sopsage.SopsAgeKeyEnv, identity.String())
+	ageKey, err := sopsage.MasterKeyFromRecipient(identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgpKey := pgp.NewMasterKeyFromFingerprint(testPgpFingerprint)
+
+	oldTree, err := storeForInputType(nil, "yaml").LoadEncryptedFile(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldData, _, err := readData(context.Background(), content, "yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	managedAge := map[string]bool{"pgp": true, "age": true}
+	for _, tc := range []struct {
+		name     string
+		group    mozillasops.KeyGroup
+		managed  map[string]bool
+		expected mozillasops.KeyGroup
+	}{
+		{"add", mozillasops.KeyGroup{pgpKey, ageKey}, managedAge, mozillasops.KeyGroup{pgpKey, ageKey}},
+		{"remove", mozillasops.KeyGroup{ageKey}, managedAge, mozillasops.KeyGroup{ageKey}},
+		{"keep unmanaged", mozillasops.KeyGroup{pgpKey}, recipientKeyTypes, mozillasops.KeyGroup{pgpKey, ageKey}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			groups, err := rekeyFile(context.Background(), filename, types.StringNull(), nil, tc.group, tc.managed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := recipientIDs([]mozillasops.KeyGroup{tc.expected})
+			if actual := recipientIDs(groups); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected recipients %v, got %v", expected, actual)
+			}
+
+			rekeyed, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fromFile, err := fileKeyGroups(filename, types.StringNull(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := recipientIDs(fromFile); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected the file to be encrypted for %v, got %v", expected, actual)
+			}
+
+			newTree, err := storeForInputType(nil, "yaml").LoadEncryptedFile(rekeyed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(oldTree.Branches, newTree.Branches) {
+				t.Error("Expected the encrypted values to be kept")
+			}
+			newData, _, err := readData(context.Background(), rekeyed, "yaml", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(oldData, newData) {
+				t.Errorf("Expected the data to be kept, expected %v, got %v", oldData, newData)
+			}
+		})
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %s", info.Mode())
+	}
+}
+
+func TestMergeKeyGroup_groups(t *testing.T) {
+	groups := []mozillasops.KeyGroup{
+		{pgp.NewMasterKeyFromFingerprint(testPgpFingerprint)},
+		{pgp.NewMasterKeyFromFingerprint("0749A11A")},
+	}
+	group := mozillasops.KeyGroup{pgp.NewMasterKeyFromFingerprint(testPgpFingerprint)}
+	if _, err := mergeKeyGroup(groups, group, recipientKeyTypes); err == nil {
+		t.Error("Expected an error for a file with several key groups")
+	}
+}
+
+func TestRecipientIDs(t *testing.T) {
+	groups := []mozillasops.KeyGroup{
+		{pgp.NewMasterKeyFromFingerprint("3ce5 cc72 19d6 597c e648 8bf1 bf36 cd3d 0749 a11a")},
+		{pgp.NewMasterKeyFromFingerprint("0749A11A")},
+	}
+	expected := []string{"0749A11A", testPgpFingerprint}
+	if actual := recipientIDs(groups); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+const configTestResourceSopsRecipients_basic = `
+resource "sops_recipients" "test" {
+  filename = "%s"
+
+  pgp {
+    fingerprint = "%s"
+  }
+}`
+
+func TestResourceSopsRecipients(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "basic.yaml")
+	if err := os.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsRecipients_basic, filename, testPgpFingerprint),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sops_recipients.test", "recipients.#", "1"),
+					resource.TestCheckTypeSetElemAttr("sops_recipients.test", "recipients.*", testPgpFingerprint),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,263 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.ResourceWithConfigure = &rotationResource{}
+
+func newRotationResource() resource.Resource {
+	return &rotationResource{}
+}
+
+type rotationResource struct {
+	stores *config.StoresConfig
+}
+
+type rotationResourceModel struct {
+	Filename       types.String `tfsdk:"filename"`
+	InputType      types.String `tfsdk:"input_type"`
+	Triggers       types.Map    `tfsdk:"triggers"`
+	RotationPeriod types.String `tfsdk:"rotation_period"`
+	LastRotated    types.String `tfsdk:"last_rotated"`
+	ID             types.String `tfsdk:"id"`
+}
+
+func (r *rotationResource) Configure(_ context.Context, req resource.ConfigureRequest, _ *resource.ConfigureResponse) {
+	r.stores = configuredStores(req.ProviderData)
+}
+
+func (r *rotationResource) Metadata(_ context.Context, _ resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = "sops_rotation"
+}
+
+func (r *rotationResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Rotate the data key of an existing sops-encrypted file, like sops -r",
+		Attributes: map[string]schema.Attribute{
+			"filename": schema.StringAttribute{
+				Description: "Path to the encrypted file",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"input_type": schema.StringAttribute{
+				Description: "Type of the file: json, yaml, dotenv, ini, toml, properties, hcl, raw. Detected from the file extension or the sops metadata by default",
+				Optional:    true,
+			},
+			"triggers": schema.MapAttribute{
+				Description: "Arbitrary values that rotate the data key when changed",
+				Optional:    true,
+				ElementType: types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"rotation_period": schema.StringAttribute{
+				Description: "Rotate the data key when this long has passed since the last rotation, e.g. 720h",
+				Optional:    true,
+				Validators: []validator.String{
+					&durationValidator{},
+				},
+			},
+
+			"last_rotated": schema.StringAttribute{
+				Description: "Time the data key was last rotated, in RFC 3339 format",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *rotationResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan rotationResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	filename := plan.Filename.ValueString()
+	lastRotated, err := rotateFile(newLogContext(ctx, filename), filename, plan.InputType, r.stores)
+	if err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("failed to rotate the data key of %s", filename), err.Error())
+		return
+	}
+
+	plan.LastRotated = types.StringValue(lastRotated.Format(time.RFC3339))
+	plan.ID = types.StringValue(filename)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *rotationResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state rotationResourceModel
+	if ds := req.State.Get(ctx, &state); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+
+	filename := state.Filename.ValueString()
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		resp.Diagnostics.AddWarning("file not found", fmt.Sprintf("%q does not exist", filename))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Removing the resource once the rotation period has passed makes
+	// Terraform plan to create it again, which rotates the data key.
+	if !state.RotationPeriod.IsNull() {
+		period, err := time.ParseDuration(state.RotationPeriod.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("invalid rotation_period", err.Error())
+			return
+		}
+		lastRotated, err := time.Parse(time.RFC3339, state.LastRotated.ValueString())
+		if err != nil || !time.Now().Before(lastRotated.Add(period)) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+	}
+}
+
+// Update only records changes to arguments that don't rotate the data key
+func (r *rotationResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan rotationResourceModel
+	if ds := req.Plan.Get(ctx, &plan); ds.HasError() {
+		resp.Diagnostics.Append(ds...)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete leaves the file in place, it isn't managed by this resource
+func (r *rotationResource) Delete(context.Context, resource.DeleteRequest, *resource.DeleteResponse) {
+}
+
+// rotateFile rotates the data key of an encrypted file in place, returning
+// the time of the rotation
+func rotateFile(ctx context.Context, filename string, inputType types.String, stores *config.StoresConfig) (time.Time, error) {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer unlock()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	format, err := resolveInputType(filename, content, inputType)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := validateInputType(format); err != nil {
+		return time.Time{}, err
+	}
+
+	rotated, lastRotated, err := rotateDataKey(ctx, content, format, storeForInputType(stores, format))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := replaceFile(filename, rotated); err != nil {
+		return time.Time{}, err
+	}
+	return lastRotated, nil
+}
+
+// rotateDataKey decrypts content with its current data key and encrypts it
+// again with a new one, for the same master keys
+func rotateDataKey(ctx context.Context, content []byte, format string, store common.Store) ([]byte, time.Time, error) {
+	tree, err := store.LoadEncryptedFile(content)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	start := time.Now()
+	recorder := newKeyServiceRecorder(ctx, "decrypt")
+	cipher := aes.NewCipher()
+	_, err = common.DecryptTree(common.DecryptTreeOpts{
+		Cipher:      cipher,
+		Tree:        &tree,
+		KeyServices: recorder.wrap(LocalKeySvc()),
+	})
+	logOperation(ctx, logDecrypt, format, recorder.keyType(), start, err)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	start = time.Now()
+	rotated, err := encryptWithNewDataKey(ctx, &tree, cipher, store)
+	logOperation(ctx, logEncrypt, format, keyGroupTypes(tree.Metadata.KeyGroups), start, err)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return rotated, tree.Metadata.LastModified, nil
+}
+
+// encryptWithNewDataKey encrypts a decrypted tree with a new data key for its
+// master keys, and emits it with store
+func encryptWithNewDataKey(ctx context.Context, tree *mozillasops.Tree, cipher mozillasops.Cipher, store common.Store) ([]byte, error) {
+	recorder := newKeyServiceRecorder(ctx, "encrypt")
+	dataKey, errs := tree.GenerateDataKeyWithKeyServices(recorder.wrap(LocalKeySvc()))
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("Could not generate data key: %s", errs)
+	}
+	if err := common.EncryptTree(common.EncryptTreeOpts{
+		DataKey: dataKey,
+		Tree:    tree,
+		Cipher:  cipher,
+	}); err != nil {
+		return nil, err
+	}
+
+	rotated, err := store.EmitEncryptedFile(*tree)
+	if err != nil {
+		return nil, fmt.Errorf("Could not marshal tree: %s", err)
+	}
+	return rotated, nil
+}
+
+type durationValidator struct{}
+
+func (v durationValidator) Description(_ context.Context) string {
+	return "must be a duration"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, res *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if d, err := time.ParseDuration(req.ConfigValue.ValueString()); err != nil || d <= 0 {
+		res.Diagnostics.AddAttributeError(req.Path, v.Description(ctx), "value must be a positive duration, such as 720h")
+	}
+}
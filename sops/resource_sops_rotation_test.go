@@ -0,0 +1,128 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestRotateFile(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "basic.yaml")
+	if err := os.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Add(-time.Second)
+	lastRotated, err := rotateFile(context.Background(), filename, types.StringNull(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastRotated.Before(before) {
+		t.Errorf("Expected the rotation time to be now, got %s", lastRotated)
+	}
+
+	rotated, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldTree, err := storeForInputType(nil, "yaml").LoadEncryptedFile(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTree, err := storeForInputType(nil, "yaml").LoadEncryptedFile(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey, err := oldTree.Metadata.GetDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := newTree.Metadata.GetDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(oldKey, newKey) {
+		t.Error("Expected the data key to change")
+	}
+	if len(newTree.Metadata.KeyGroups) != 1 || len(newTree.Metadata.KeyGroups[0]) != 1 {
+		t.Errorf("Expected the master keys to be kept, got %v", newTree.Metadata.KeyGroups)
+	}
+
+	oldData, _, err := readData(context.Background(), content, "yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newData, _, err := readData(context.Background(), rotated, "yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(oldData, newData) {
+		t.Errorf("Expected the data to be kept, expected %v, got %v", oldData, newData)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %s", info.Mode())
+	}
+}
+
+const configTestResourceSopsRotation_basic = `
+resource "sops_rotation" "test" {
+  filename = "%s"
+  triggers = {
+    version = "%s"
+  }
+}`
+
+func TestResourceSopsRotation(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "basic.yaml")
+	if err := os.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var lastRotated string
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsRotation_basic, filename, "1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrWith("sops_rotation.test", "last_rotated", func(v string) error {
+						lastRotated = v
+						return nil
+					}),
+				),
+			},
+			{
+				PreConfig: func() { time.Sleep(time.Second) },
+				Config:    fmt.Sprintf(configTestResourceSopsRotation_basic, filename, "2"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrWith("sops_rotation.test", "last_rotated", func(v string) error {
+						if v == lastRotated {
+							return fmt.Errorf("expected the data key to be rotated again")
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
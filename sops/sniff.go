@@ -0,0 +1,62 @@
+package sops
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sniffInputType determines the input type of sops-encrypted content from the
+// layout of its sops metadata. Content encrypted in binary mode has no
+// structure sops knows about, and is detected as raw.
+func sniffInputType(content []byte) (string, error) {
+	var jsonData map[string]interface{}
+	if err := json.Unmarshal(content, &jsonData); err == nil {
+		if _, ok := jsonData["sops"]; ok {
+			if _, ok := jsonData["data"].(string); ok && len(jsonData) == 2 {
+				return "raw", nil
+			}
+			return "json", nil
+		}
+	}
+
+	if hasYamlSopsKey(content) {
+		return "yaml", nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "[sops]" {
+			return "ini", nil
+		}
+		if strings.HasPrefix(line, "sops_") && strings.Contains(line, "=") {
+			return "dotenv", nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not detect the format of the sops-encrypted data, set input_type as appropriate")
+}
+
+// hasYamlSopsKey reports whether any document in content is a map with a
+// top-level sops key
+func hasYamlSopsKey(content []byte) bool {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err == io.EOF {
+			return false
+		} else if err != nil {
+			return false
+		}
+		if _, ok := doc["sops"]; ok {
+			return true
+		}
+	}
+}
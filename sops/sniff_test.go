@@ -0,0 +1,75 @@
+package sops
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSniffInputType(t *testing.T) {
+	tc := []struct {
+		file     string
+		expected string
+	}{
+		{"test-fixtures/basic.json", "json"},
+		{"test-fixtures/basic.yaml", "yaml"},
+		{"test-fixtures/multi-document.yaml", "yaml"},
+		{"test-fixtures/sections.ini", "ini"},
+		{"test-fixtures/raw.txt", "raw"},
+		{"test-fixtures/secrets.toml", "raw"},
+	}
+	for _, c := range tc {
+		t.Run(c.file, func(t *testing.T) {
+			content, err := os.ReadFile(c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			format, err := sniffInputType(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != c.expected {
+				t.Errorf("Expected %s, got %s", c.expected, format)
+			}
+		})
+	}
+}
+
+func TestSniffInputType_dotenv(t *testing.T) {
+	content := []byte("password=ENC[AES256_GCM,data:abc,type:str]\nsops_version=3.10.2\n")
+	format, err := sniffInputType(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "dotenv" {
+		t.Errorf("Expected dotenv, got %s", format)
+	}
+}
+
+func TestSniffInputType_unencrypted(t *testing.T) {
+	for _, content := range []string{`{"password": "foo"}`, "password: foo", "password=foo", "[db]\npassword=foo"} {
+		if format, err := sniffInputType([]byte(content)); err == nil {
+			t.Errorf("Expected an error for %q, got %s", content, format)
+		}
+	}
+}
+
+func TestInputTypeForPath(t *testing.T) {
+	tc := []struct {
+		filename string
+		expected string
+	}{
+		{"app.yaml", "yaml"},
+		{"app.enc.yaml", "yaml"},
+		{"app.yaml.enc", "yaml"},
+		{"secrets.json.sops", "json"},
+		{"dir.d/secrets.enc.tfvars", "hcl"},
+		{"app.enc", ""},
+		{"app.txt", ""},
+	}
+	for _, c := range tc {
+		format, ok := inputTypeForPath(c.filename)
+		if format != c.expected || ok != (c.expected != "") {
+			t.Errorf("Unexpected input type for %s, expected %q, got %q", c.filename, c.expected, format)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package sops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getsops/sops/v3/cmd/sops/codes"
+	"github.com/getsops/sops/v3/keyservice"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"google.golang.org/grpc"
+)
+
+// sopsError is an error along with the exit code the sops binary reports it
+// with, which tells what failed
+type sopsError struct {
+	code int
+	err  error
+}
+
+func (e *sopsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sopsError) Unwrap() error {
+	return e.err
+}
+
+func (e *sopsError) ExitCode() int {
+	return e.code
+}
+
+// exitCodeSummaries describe the exit codes of the errors returned while
+// decrypting and encrypting
+var exitCodeSummaries = map[int]string{
+	codes.CouldNotReadInputFile: "could not read the input",
+	codes.ErrorDumpingTree:      "could not write the output",
+	codes.CouldNotRetrieveKey:   "could not decrypt the data key",
+	codes.ErrorDecryptingTree:   "could not decrypt the values",
+	codes.ErrorDecryptingMac:    "could not decrypt the MAC",
+	codes.MacMismatch:           "the file failed its integrity check",
+	codes.FileAlreadyEncrypted:  "the content is already encrypted",
+}
+
+// keyAttempt is the result of encrypting or decrypting the data key with a
+// master key
+type keyAttempt struct {
+	keyType string
+	id      string
+	err     error
+}
+
+// keyServiceRecorder records and logs the attempts of key services to
+// encrypt or decrypt the data key, which sops only reports as a single
+// message
+type keyServiceRecorder struct {
+	// ctx is the context attempts are logged to, as sops calls key services
+	// with a context of its own
+	ctx context.Context
+	// op is encrypt or decrypt
+	op       string
+	attempts []keyAttempt
+}
+
+func newKeyServiceRecorder(ctx context.Context, op string) *keyServiceRecorder {
+	return &keyServiceRecorder{ctx: ctx, op: op}
+}
+
+// wrap returns svcs recording their results to r
+func (r *keyServiceRecorder) wrap(svcs []keyservice.KeyServiceClient) []keyservice.KeyServiceClient {
+	wrapped := make([]keyservice.KeyServiceClient, len(svcs))
+	for i, svc := range svcs {
+		wrapped[i] = &recordingKeyService{KeyServiceClient: svc, recorder: r}
+	}
+	return wrapped
+}
+
+func (r *keyServiceRecorder) record(key *keyservice.Key, err error, duration time.Duration) {
+	keyType, id := describeKey(key)
+	a := keyAttempt{keyType: keyType, id: id, err: err}
+	r.attempts = append(r.attempts, a)
+	logKeyAttempt(r.ctx, r.op, a, duration)
+}
+
+// keyType returns the type of the first master key that succeeded
+func (r *keyServiceRecorder) keyType() string {
+	for _, a := range r.attempts {
+		if a.err == nil {
+			return a.keyType
+		}
+	}
+	return ""
+}
+
+// failure returns an error listing the failed attempts. err is the error of
+// sops, which may be nil if the attempts tell all there is to know.
+func (r *keyServiceRecorder) failure(err error) *masterKeyError {
+	e := &masterKeyError{op: r.op, err: err}
+	for _, a := range r.attempts {
+		if a.err != nil {
+			e.failed = append(e.failed, a)
+		}
+	}
+	return e
+}
+
+type recordingKeyService struct {
+	keyservice.KeyServiceClient
+	recorder *keyServiceRecorder
+}
+
+func (s *recordingKeyService) Encrypt(ctx context.Context, req *keyservice.EncryptRequest, opts ...grpc.CallOption) (*keyservice.EncryptResponse, error) {
+	start := time.Now()
+	resp, err := s.KeyServiceClient.Encrypt(ctx, req, opts...)
+	s.recorder.record(req.Key, err, time.Since(start))
+	return resp, err
+}
+
+func (s *recordingKeyService) Decrypt(ctx context.Context, req *keyservice.DecryptRequest, opts ...grpc.CallOption) (*keyservice.DecryptResponse, error) {
+	start := time.Now()
+	resp, err := s.KeyServiceClient.Decrypt(ctx, req, opts...)
+	s.recorder.record(req.Key, err, time.Since(start))
+	return resp, err
+}
+
+// describeKey returns the type of a master key, as named in sops metadata,
+// and its identifier
+func describeKey(key *keyservice.Key) (keyType, id string) {
+	switch {
+	case key.GetAgeKey() != nil:
+		return "age", key.GetAgeKey().GetRecipient()
+	case key.GetPgpKey() != nil:
+		return "pgp", key.GetPgpKey().GetFingerprint()
+	case key.GetKmsKey() != nil:
+		k := key.GetKmsKey()
+		id = k.GetArn()
+		if k.GetAwsProfile() != "" {
+			id += fmt.Sprintf(" (profile %s)", k.GetAwsProfile())
+		}
+		return "kms", id
+	case key.GetGcpKmsKey() != nil:
+		return "gcp_kms", key.GetGcpKmsKey().GetResourceId()
+	case key.GetAzureKeyvaultKey() != nil:
+		k := key.GetAzureKeyvaultKey()
+		return "azure_kv", fmt.Sprintf("%s/keys/%s/%s", k.GetVaultUrl(), k.GetName(), k.GetVersion())
+	case key.GetVaultKey() != nil:
+		k := key.GetVaultKey()
+		return "hc_vault", fmt.Sprintf("%s/v1/%s/keys/%s", k.GetVaultAddress(), k.GetEnginePath(), k.GetKeyName())
+	default:
+		return "unknown", ""
+	}
+}
+
+// credentialHints tell which credentials a master key type needs
+var credentialHints = map[string]string{
+	"age":      "an age identity matching the recipient in SOPS_AGE_KEY, SOPS_AGE_KEY_FILE, SOPS_AGE_KEY_CMD or the default keys.txt",
+	"pgp":      "the secret key in the GnuPG keyring (GNUPGHOME) and a running gpg-agent",
+	"kms":      "AWS credentials for the profile or environment (AWS_PROFILE, AWS_ACCESS_KEY_ID), allowed to use the key",
+	"gcp_kms":  "Google Cloud credentials (GOOGLE_APPLICATION_CREDENTIALS), allowed to use the key",
+	"azure_kv": "Azure credentials (AZURE_CLIENT_ID, AZURE_TENANT_ID, az login), allowed to use the key",
+	"hc_vault": "the Vault token (VAULT_TOKEN or ~/.vault-token), allowed to use the transit key",
+}
+
+// masterKeyError is a failure to encrypt or decrypt the data key, listing
+// each master key that failed
+type masterKeyError struct {
+	// op is encrypt or decrypt
+	op     string
+	failed []keyAttempt
+	err    error
+}
+
+func (e *masterKeyError) Error() string {
+	msg := fmt.Sprintf("Could not %s the data key", e.op)
+	if e.err != nil {
+		msg += ": " + e.err.Error()
+	}
+	for _, a := range e.failed {
+		msg += fmt.Sprintf("\n- %s key %s: %s", a.keyType, a.id, a.err)
+	}
+	return msg
+}
+
+func (e *masterKeyError) Unwrap() error {
+	return e.err
+}
+
+// hint lists the credentials to check for the key types that failed
+func (e *masterKeyError) hint() string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, a := range e.failed {
+		if seen[a.keyType] || credentialHints[a.keyType] == "" {
+			continue
+		}
+		seen[a.keyType] = true
+		lines = append(lines, fmt.Sprintf("- %s: %s", a.keyType, credentialHints[a.keyType]))
+	}
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Check the credentials for:\n" + strings.Join(lines, "\n")
+}
+
+// addSopsErrors adds err to diags. Failures of master keys are added as a
+// diagnostic for each key, followed by one listing the credentials to check.
+func addSopsErrors(diags *diag.Diagnostics, summary string, err error) {
+	var keyErr *masterKeyError
+	if errors.As(err, &keyErr) {
+		for _, a := range keyErr.failed {
+			diags.AddError(
+				fmt.Sprintf("%s: %s key failed", summary, a.keyType),
+				fmt.Sprintf("The %s key %s could not %s the data key: %s", a.keyType, a.id, keyErr.op, a.err),
+			)
+		}
+		var detail string
+		if keyErr.op == "encrypt" {
+			detail = fmt.Sprintf("The data key must be encrypted with every master key, and %d failed.", len(keyErr.failed))
+		} else {
+			detail = "The data key could not be recovered with the master keys of the file."
+		}
+		if keyErr.err != nil {
+			detail += fmt.Sprintf(" sops reported: %s", keyErr.err)
+		}
+		if hint := keyErr.hint(); hint != "" {
+			detail += "\n\n" + hint
+		}
+		diags.AddError(fmt.Sprintf("%s: could not %s the data key", summary, keyErr.op), detail)
+		return
+	}
+
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		if s, ok := exitCodeSummaries[coder.ExitCode()]; ok {
+			summary = fmt.Sprintf("%s: %s", summary, s)
+		}
+	}
+	diags.AddError(summary, err.Error())
+}
@@ -0,0 +1,120 @@
+package sops
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/cmd/sops/codes"
+	"github.com/getsops/sops/v3/keyservice"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"google.golang.org/grpc"
+)
+
+func TestDecryptData_keyFailures(t *testing.T) {
+	encryptedFor, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := encryptedFor.Recipient().String()
+	ageKey, err := sopsage.MasterKeyFromRecipient(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := storeForInputType(nil, "yaml")
+	content, err := Encrypt(context.Background(), EncryptOpts{
+		Cipher:      aes.NewCipher(),
+		InputStore:  store,
+		OutputStore: store,
+		InputPath:   "secrets.yaml",
+		KeyServices: LocalKeySvc(),
+		KeyGroups:   []mozillasops.KeyGroup{{ageKey}},
+	}, []byte("password: secret\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(sopsage.SopsAgeKeyEnv, other.String())
+	_, err = decryptData(context.Background(), content, "yaml", nil)
+	if err == nil {
+		t.Fatal("Expected decrypting with another identity to fail")
+	}
+	var coder interface{ ExitCode() int }
+	if !errors.As(err, &coder) || coder.ExitCode() != codes.CouldNotRetrieveKey {
+		t.Errorf("Expected exit code %d, got %v", codes.CouldNotRetrieveKey, err)
+	}
+	var keyErr *masterKeyError
+	if !errors.As(err, &keyErr) {
+		t.Fatalf("Expected a master key error, got %v", err)
+	}
+	if len(keyErr.failed) != 1 || keyErr.failed[0].keyType != "age" || keyErr.failed[0].id != recipient {
+		t.Errorf("Expected the age key %s to have failed, got %+v", recipient, keyErr.failed)
+	}
+
+	var diags diag.Diagnostics
+	addSopsErrors(&diags, "Error reading data", err)
+	if len(diags) != 2 {
+		t.Fatalf("Expected a diagnostic for the key and a summary, got %v", diags)
+	}
+	if !strings.Contains(diags[0].Detail(), recipient) {
+		t.Errorf("Expected the key diagnostic to name %s, got %q", recipient, diags[0].Detail())
+	}
+	if !strings.Contains(diags[1].Detail(), sopsage.SopsAgeKeyEnv) {
+		t.Errorf("Expected the summary to hint at %s, got %q", sopsage.SopsAgeKeyEnv, diags[1].Detail())
+	}
+}
+
+type failingKeyService struct{}
+
+func (failingKeyService) Encrypt(context.Context, *keyservice.EncryptRequest, ...grpc.CallOption) (*keyservice.EncryptResponse, error) {
+	return nil, errors.New("access denied")
+}
+
+func (failingKeyService) Decrypt(context.Context, *keyservice.DecryptRequest, ...grpc.CallOption) (*keyservice.DecryptResponse, error) {
+	return nil, errors.New("access denied")
+}
+
+func TestEncrypt_keyFailures(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ageKey, err := sopsage.MasterKeyFromRecipient(identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := storeForInputType(nil, "yaml")
+	_, err = Encrypt(context.Background(), EncryptOpts{
+		Cipher:      aes.NewCipher(),
+		InputStore:  store,
+		OutputStore: store,
+		InputPath:   "secrets.yaml",
+		KeyServices: []keyservice.KeyServiceClient{failingKeyService{}},
+		KeyGroups:   []mozillasops.KeyGroup{{ageKey}},
+	}, []byte("password: secret\n"))
+
+	var keyErr *masterKeyError
+	if !errors.As(err, &keyErr) {
+		t.Fatalf("Expected a master key error, got %v", err)
+	}
+	if keyErr.op != "encrypt" || len(keyErr.failed) != 1 || keyErr.failed[0].err.Error() != "access denied" {
+		t.Errorf("Expected the age key to have failed, got %+v", keyErr)
+	}
+}
+
+func TestAddSopsErrors_exitCode(t *testing.T) {
+	var diags diag.Diagnostics
+	addSopsErrors(&diags, "Error reading data", &sopsError{code: codes.MacMismatch, err: errors.New("mismatch")})
+	if len(diags) != 1 || diags[0].Summary() != "Error reading data: the file failed its integrity check" {
+		t.Errorf("Unexpected diagnostics %v", diags)
+	}
+}
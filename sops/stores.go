@@ -0,0 +1,77 @@
+package sops
+
+import (
+	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// providerData is handed from the provider to resources and data sources
+type providerData struct {
+	encryptConfig encryptConfigModel
+	stores        *config.StoresConfig
+}
+
+// configuredStores returns the store configuration from the provider data,
+// or the sops defaults if the provider isn't configured yet
+func configuredStores(data interface{}) *config.StoresConfig {
+	if pd, ok := data.(providerData); ok && pd.stores != nil {
+		return pd.stores
+	}
+	return defaultStoreConfig
+}
+
+type storesConfigModel struct {
+	JSON       *storeConfigModel `tfsdk:"json"`
+	JSONBinary *storeConfigModel `tfsdk:"json_binary"`
+	YAML       *storeConfigModel `tfsdk:"yaml"`
+}
+
+type storeConfigModel struct {
+	Indent types.Int64 `tfsdk:"indent"`
+}
+
+func storesBlock() schema.Block {
+	storeBlock := func(description string) schema.Block {
+		return schema.SingleNestedBlock{
+			Description: description,
+			Attributes: map[string]schema.Attribute{
+				"indent": schema.Int64Attribute{
+					Description: "Number of spaces to indent with",
+					Optional:    true,
+					Validators: []validator.Int64{
+						int64validator.AtLeast(0),
+					},
+				},
+			},
+		}
+	}
+	return schema.SingleNestedBlock{
+		Description: "Formatting of files written and decrypted, as in the stores section of .sops.yaml",
+		Blocks: map[string]schema.Block{
+			"json":        storeBlock("Options for JSON files. Indents with a tab by default"),
+			"json_binary": storeBlock("Options for files encrypted in binary mode. Indents with a tab by default"),
+			"yaml":        storeBlock("Options for YAML files. Indents with 4 spaces by default"),
+		},
+	}
+}
+
+// storesConfig converts the stores block to the sops store configuration
+func (m *storesConfigModel) storesConfig() *config.StoresConfig {
+	c := config.NewStoresConfig()
+	if m == nil {
+		return c
+	}
+	if m.JSON != nil && !m.JSON.Indent.IsNull() {
+		c.JSON.Indent = int(m.JSON.Indent.ValueInt64())
+	}
+	if m.JSONBinary != nil && !m.JSONBinary.Indent.IsNull() {
+		c.JSONBinary.Indent = int(m.JSONBinary.Indent.ValueInt64())
+	}
+	if m.YAML != nil && !m.YAML.Indent.IsNull() {
+		c.YAML.Indent = int(m.YAML.Indent.ValueInt64())
+	}
+	return c
+}
@@ -1,15 +1,66 @@
 package sops
 
 import (
+	"path"
+	"strings"
+
 	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
 	"github.com/getsops/sops/v3/config"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 var defaultStoreConfig = config.NewStoresConfig()
 
-func GetInputStore(filename string) common.Store {
-	return common.DefaultStoreForPathOrFormat(defaultStoreConfig, filename, "file")
+func GetInputStore(stores *config.StoresConfig, filename string) common.Store {
+	return common.DefaultStoreForPathOrFormat(stores, filename, "file")
+}
+func GetOutputStore(stores *config.StoresConfig, filename string) common.Store {
+	return common.DefaultStoreForPathOrFormat(stores, filename, "file")
 }
-func GetOutputStore(filename string) common.Store {
-	return common.DefaultStoreForPathOrFormat(defaultStoreConfig, filename, "file")
+
+// storeForInputType returns the store able to load files of the given input type.
+// Input types sops doesn't know about (such as raw) use the binary store. The
+// default store configuration is used if stores is nil.
+func storeForInputType(stores *config.StoresConfig, inputType string) common.Store {
+	if stores == nil {
+		stores = defaultStoreConfig
+	}
+	return common.StoreForFormat(formats.FormatFromString(parsers[inputType].store), stores)
+}
+
+// resolveInputType returns inputType if set, and otherwise determines the
+// input type of the file from its extension, falling back to the layout of
+// its content
+func resolveInputType(filename string, content []byte, inputType types.String) (string, error) {
+	if !inputType.IsNull() {
+		return inputType.ValueString(), nil
+	}
+	if format, ok := inputTypeForPath(filename); ok {
+		return format, nil
+	}
+	return sniffInputType(content)
+}
+
+// encryptedExtensions are extensions commonly added to the name of encrypted
+// files, such as app.yaml.enc. They are skipped when detecting the input type.
+var encryptedExtensions = map[string]bool{
+	".enc":  true,
+	".sops": true,
+}
+
+// inputTypeForPath determines the input type of a file from its extension
+func inputTypeForPath(filename string) (string, bool) {
+	ext := path.Ext(filename)
+	if encryptedExtensions[ext] {
+		ext = path.Ext(strings.TrimSuffix(filename, ext))
+	}
+	for inputType, p := range parsers {
+		for _, e := range p.extensions {
+			if e == ext {
+				return inputType, true
+			}
+		}
+	}
+	return "", false
 }
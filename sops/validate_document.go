@@ -0,0 +1,134 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// validateDocument checks the decrypted tree of doc against the required
+// keys and JSON Schema configured on a data source. For decoded documents,
+// the decoded values are checked. Only keys and paths are reported, never
+// values.
+func validateDocument(ctx context.Context, doc document, requiredKeys types.List, schema types.String, opts readOptions, diags *diag.Diagnostics) {
+	if !requiredKeys.IsNull() {
+		var keys []string
+		if ds := requiredKeys.ElementsAs(ctx, &keys, false); ds.HasError() {
+			diags.Append(ds...)
+			return
+		}
+		missing, err := missingKeys(doc, keys, opts.flattener)
+		if err != nil {
+			diags.AddAttributeError(tfpath.Root("required_keys"), "Invalid required key", err.Error())
+			return
+		}
+		if len(missing) > 0 {
+			diags.AddAttributeError(
+				tfpath.Root("required_keys"),
+				"Missing required keys",
+				fmt.Sprintf("The decrypted data has no value for: %s", strings.Join(missing, ", ")),
+			)
+		}
+	}
+
+	if !schema.IsNull() {
+		violations, err := schemaViolations(doc.tree, schema.ValueString())
+		if err != nil {
+			diags.AddAttributeError(tfpath.Root("schema"), "Invalid schema", err.Error())
+			return
+		}
+		for _, v := range violations {
+			diags.AddAttributeError(tfpath.Root("schema"), "Decrypted data doesn't match the schema", v)
+		}
+	}
+}
+
+// missingKeys returns the keys, flattened by f, that have no value in the
+// tree of doc. Keys with a null value count as missing. The keys of decoded
+// documents aren't flattened, so they are looked up as is.
+func missingKeys(doc document, keys []string, f *flattener) ([]string, error) {
+	var missing []string
+	for _, key := range keys {
+		path := []string{key}
+		if !doc.decoded {
+			var err error
+			path, err = splitKeyPath(key, f.separator, f.escapeKeys)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if lookupPath(doc.tree, path) == nil {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
+
+// lookupPath returns the value at path in tree, or nil if there is none
+func lookupPath(tree map[string]interface{}, path []string) interface{} {
+	var current interface{} = tree
+	for _, key := range path {
+		switch typed := normalizeValue(current).(type) {
+		case map[string]interface{}:
+			current = typed[key]
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(typed) {
+				return nil
+			}
+			current = typed[idx]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// schemaViolations validates tree against a JSON Schema, returning the paths
+// that don't match and why
+func schemaViolations(tree map[string]interface{}, schema string) ([]string, error) {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		tree = map[string]interface{}{}
+	}
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(normalizeTree(tree)))
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, e := range result.Errors() {
+		violations = append(violations, fmt.Sprintf("%s: %s", e.Field(), e.Description()))
+	}
+	return violations, nil
+}
+
+// normalizeTree converts all maps in v to map[string]interface{}, which can
+// be encoded as JSON
+func normalizeTree(v interface{}) interface{} {
+	switch typed := normalizeValue(v).(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			ret[k] = normalizeTree(v)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(typed))
+		for i, v := range typed {
+			ret[i] = normalizeTree(v)
+		}
+		return ret
+	default:
+		return typed
+	}
+}
@@ -0,0 +1,120 @@
+package sops
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestMissingKeys(t *testing.T) {
+	tree := map[string]interface{}{
+		"db": map[string]interface{}{
+			"user":     "foo",
+			"password": nil,
+		},
+		"hosts": []interface{}{"a", "b"},
+	}
+	missing, err := missingKeys(document{tree: tree}, []string{"db.user", "db.password", "db.port", "hosts.1", "hosts.2", "db.user.name"}, newFlattener(".", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"db.password", "db.port", "hosts.2", "db.user.name"}
+	if !reflect.DeepEqual(expected, missing) {
+		t.Errorf("Expected %v, got %v", expected, missing)
+	}
+}
+
+func TestValidateDocument_schema(t *testing.T) {
+	doc := document{tree: map[string]interface{}{
+		"db": map[interface{}]interface{}{
+			"password": "hunter2",
+			"port":     "not-a-port",
+		},
+	}}
+	schema := `{
+		"type": "object",
+		"required": ["db"],
+		"properties": {
+			"db": {
+				"type": "object",
+				"required": ["user"],
+				"properties": {
+					"password": {"type": "string", "minLength": 12},
+					"port": {"type": "integer"}
+				}
+			}
+		}
+	}`
+
+	var diags diag.Diagnostics
+	validateDocument(context.Background(), doc, types.ListNull(types.StringType), types.StringValue(schema), readOptions{flattener: newFlattener(".", false)}, &diags)
+	if diags.ErrorsCount() != 3 {
+		t.Fatalf("Expected 3 errors, got %v", diags)
+	}
+	for _, d := range diags {
+		if strings.Contains(d.Detail(), "hunter2") || strings.Contains(d.Detail(), "not-a-port") {
+			t.Errorf("Expected no values in the error, got %q", d.Detail())
+		}
+	}
+	for _, field := range []string{"db.password", "db.port", "db: user is required"} {
+		found := false
+		for _, d := range diags {
+			found = found || strings.Contains(d.Detail(), field)
+		}
+		if !found {
+			t.Errorf("Expected an error about %s, got %v", field, diags)
+		}
+	}
+}
+
+func TestValidateDocument_requiredKeys(t *testing.T) {
+	doc := document{tree: map[string]interface{}{"db": map[string]interface{}{"user": "foo"}}}
+	required, _ := types.ListValueFrom(context.Background(), types.StringType, []string{"db.user", "db.password"})
+
+	var diags diag.Diagnostics
+	validateDocument(context.Background(), doc, required, types.StringNull(), readOptions{flattener: newFlattener(".", false)}, &diags)
+	if diags.ErrorsCount() != 1 || !strings.HasSuffix(diags[0].Detail(), ": db.password") {
+		t.Errorf("Expected an error naming db.password, got %v", diags)
+	}
+}
+
+func TestValidateDocument_decoded(t *testing.T) {
+	content, err := os.ReadFile("test-fixtures/kubernetes-secrets.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := readOptions{flattener: newFlattener(defaultKeySeparator, false)}
+	docs, _, err := readDocuments(context.Background(), content, "yaml", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeKubernetesSecrets(docs); err != nil {
+		t.Fatal(err)
+	}
+	required, _ := types.ListValueFrom(context.Background(), types.StringType, []string{"password", "username", "data.password"})
+	schema := `{
+		"type": "object",
+		"required": ["password"],
+		"properties": {
+			"password": {"type": "string"}
+		},
+		"additionalProperties": {"type": "string", "maxLength": 3}
+	}`
+
+	var diags diag.Diagnostics
+	validateDocument(context.Background(), docs[0], required, types.StringValue(schema), opts, &diags)
+	if diags.ErrorsCount() != 2 {
+		t.Fatalf("Expected 2 errors, got %v", diags)
+	}
+	if !strings.HasSuffix(diags[0].Detail(), ": data.password") {
+		t.Errorf("Expected an error naming data.password, got %v", diags[0])
+	}
+	if !strings.Contains(diags[1].Detail(), "username") {
+		t.Errorf("Expected an error about username, got %v", diags[1])
+	}
+}
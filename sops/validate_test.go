@@ -31,3 +31,18 @@ func TestValidateInputType_bad(t *testing.T) {
 		t.Errorf("Failed to validate input type %s, expected to be invalid but was valid", inputType)
 	}
 }
+
+func TestValidateInputType_toml(t *testing.T) {
+	inputType := "toml"
+	testValidateInputType(inputType, t)
+}
+
+func TestValidateInputType_properties(t *testing.T) {
+	inputType := "properties"
+	testValidateInputType(inputType, t)
+}
+
+func TestValidateInputType_hcl(t *testing.T) {
+	inputType := "hcl"
+	testValidateInputType(inputType, t)
+}
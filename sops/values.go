@@ -0,0 +1,102 @@
+package sops
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// assembleValues combines secret and public values into a single document.
+// sops selects the values to encrypt by key name, so it also returns a regex
+// matching the keys of the secret leaves, or one matching the keys of the
+// public leaves when the former would also match public leaves. Exactly one
+// of the regexes is set when there are public values.
+func assembleValues(secret, public map[string]interface{}) (doc map[string]interface{}, encryptedRegex, unencryptedRegex string, err error) {
+	doc = map[string]interface{}{}
+	if err := mergeDisjoint(doc, secret, nil); err != nil {
+		return nil, "", "", err
+	}
+	if err := mergeDisjoint(doc, public, nil); err != nil {
+		return nil, "", "", err
+	}
+	if len(public) == 0 {
+		return doc, "", "", nil
+	}
+
+	secretPaths := leafPaths(secret, nil)
+	publicPaths := leafPaths(public, nil)
+	if regex, ok := leafKeyRegex(secretPaths, publicPaths); ok {
+		return doc, regex, "", nil
+	}
+	if regex, ok := leafKeyRegex(publicPaths, secretPaths); ok {
+		return doc, "", regex, nil
+	}
+	return nil, "", "", fmt.Errorf("Secret and public values can't be told apart by key name, as sops requires. Rename keys so that no key of a secret value is also used in the path of a public value, or the other way around")
+}
+
+// mergeDisjoint merges src into dst, failing if both hold a value at the same
+// path. Maps in src are copied, so that merging never modifies src.
+func mergeDisjoint(dst, src map[string]interface{}, path []string) error {
+	for k, v := range src {
+		existing, ok := dst[k]
+		if !ok {
+			if m, isMap := v.(map[string]interface{}); isMap {
+				copied := map[string]interface{}{}
+				if err := mergeDisjoint(copied, m, append(path, k)); err != nil {
+					return err
+				}
+				v = copied
+			}
+			dst[k] = v
+			continue
+		}
+		existingMap, ok := existing.(map[string]interface{})
+		vMap, vOk := v.(map[string]interface{})
+		if !ok || !vOk {
+			return fmt.Errorf("%s is set in both secret_values and public_values", strings.Join(append(path, k), "."))
+		}
+		if err := mergeDisjoint(existingMap, vMap, append(path, k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leafPaths returns the keys leading to every leaf of v. Lists are leaves, as
+// sops selects the values to encrypt by key name and list items have none.
+func leafPaths(v map[string]interface{}, path []string) [][]string {
+	var paths [][]string
+	for k, v := range v {
+		p := append(append([]string{}, path...), k)
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			paths = append(paths, leafPaths(m, p)...)
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// leafKeyRegex returns a regex matching the last key of every path in
+// selected, if it doesn't match any key in the paths in others
+func leafKeyRegex(selected, others [][]string) (string, bool) {
+	keys := map[string]bool{}
+	for _, p := range selected {
+		keys[p[len(p)-1]] = true
+	}
+	for _, p := range others {
+		for _, k := range p {
+			if keys[k] {
+				return "", false
+			}
+		}
+	}
+
+	quoted := make([]string, 0, len(keys))
+	for k := range keys {
+		quoted = append(quoted, regexp.QuoteMeta(k))
+	}
+	sort.Strings(quoted)
+	return "^(" + strings.Join(quoted, "|") + ")$", true
+}
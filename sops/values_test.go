@@ -0,0 +1,134 @@
+package sops
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAssembleValues(t *testing.T) {
+	tc := []struct {
+		name             string
+		secret           map[string]interface{}
+		public           map[string]interface{}
+		encryptedRegex   string
+		unencryptedRegex string
+	}{
+		{
+			name:   "secret only",
+			secret: map[string]interface{}{"password": "foo"},
+		},
+		{
+			name:           "secret keys",
+			secret:         map[string]interface{}{"db": map[string]interface{}{"password": "foo"}, "token": "bar"},
+			public:         map[string]interface{}{"db": map[string]interface{}{"host": "db.example"}},
+			encryptedRegex: "^(password|token)$",
+		},
+		{
+			name:             "public keys when a secret key is used in public paths",
+			secret:           map[string]interface{}{"db": map[string]interface{}{"password": "foo"}, "api": map[string]interface{}{"password": "bar"}},
+			public:           map[string]interface{}{"password": map[string]interface{}{"min_length": 12}},
+			unencryptedRegex: "^(min_length)$",
+		},
+		{
+			name:           "keys are escaped",
+			secret:         map[string]interface{}{"a.b": "foo"},
+			public:         map[string]interface{}{"c": "bar"},
+			encryptedRegex: `^(a\.b)$`,
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			_, encryptedRegex, unencryptedRegex, err := assembleValues(c.secret, c.public)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encryptedRegex != c.encryptedRegex || unencryptedRegex != c.unencryptedRegex {
+				t.Errorf("Unexpected regexes, expected %q and %q, got %q and %q", c.encryptedRegex, c.unencryptedRegex, encryptedRegex, unencryptedRegex)
+			}
+		})
+	}
+}
+
+func TestAssembleValues_errors(t *testing.T) {
+	tc := []struct {
+		name   string
+		secret map[string]interface{}
+		public map[string]interface{}
+	}{
+		{
+			name:   "same leaf",
+			secret: map[string]interface{}{"db": map[string]interface{}{"password": "foo"}},
+			public: map[string]interface{}{"db": map[string]interface{}{"password": "bar"}},
+		},
+		{
+			name:   "same key names",
+			secret: map[string]interface{}{"db": map[string]interface{}{"password": "foo"}},
+			public: map[string]interface{}{"api": map[string]interface{}{"password": "bar"}},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			if _, _, _, err := assembleValues(c.secret, c.public); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// Only the secret leaves must be encrypted, and the whole document must
+// decrypt back to the assembled values.
+func TestAssembleValues_encrypt(t *testing.T) {
+	secret := map[string]interface{}{"db": map[string]interface{}{"password": "s3cr3t"}, "api": map[string]interface{}{"password": "t0k3n"}}
+	public := map[string]interface{}{"db": map[string]interface{}{"host": "db.example", "port": int64(5432)}, "password": map[string]interface{}{"min_length": int64(12)}}
+	doc, encryptedRegex, unencryptedRegex, err := assembleValues(secret, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := fileResourceAPIModel{
+		Filename:         "secrets.yaml",
+		InputType:        "json",
+		EncryptedRegex:   encryptedRegex,
+		UnencryptedRegex: unencryptedRegex,
+		EncryptConfig: encryptConfigModel{
+			EncryptionProvider: "pgp",
+			Pgp:                PgpConf{Fingerprint: "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"},
+		},
+	}
+	encrypted, err := sopsEncrypt(context.Background(), model, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cleartext := range []string{"s3cr3t", "t0k3n"} {
+		if strings.Contains(string(encrypted), cleartext) {
+			t.Errorf("Secret value %q is not encrypted", cleartext)
+		}
+	}
+	for _, cleartext := range []string{"host: db.example", "port: 5432", "min_length: 12"} {
+		if !strings.Contains(string(encrypted), cleartext) {
+			t.Errorf("Public value %q is not in cleartext", cleartext)
+		}
+	}
+
+	data, _, err := readData(context.Background(), encrypted, "yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"db.password":         "s3cr3t",
+		"db.host":             "db.example",
+		"db.port":             "5432",
+		"api.password":        "t0k3n",
+		"password.min_length": "12",
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Errorf("Unexpected data, expected %v, got %v", expected, data)
+	}
+}